@@ -1,41 +1,181 @@
 package main
 
 import (
+    "encoding/json"
+    "errors"
     "flag"
     "fmt"
     "os"
     "path/filepath"
+    "slices"
+    "strings"
 
+    "kubectl-tree/pkg/check"
     "kubectl-tree/pkg/k8s"
     "kubectl-tree/pkg/tree"
+    "kubectl-tree/pkg/tui"
     "kubectl-tree/pkg/util"
     "k8s.io/client-go/util/homedir"
 )
 
 const version = "1.0.0"
 
+// Exit codes, so scripts can tell failures apart
+const (
+    exitError             = 1 // Any failure not listed below
+    exitUsage             = 2 // Invalid flags, as for flag parsing errors
+    exitNamespaceNotFound = 3
+    exitUnauthorized      = 4 // Credentials missing or rejected
+    exitForbidden         = 5 // Permission denied
+    exitTimeout           = 6 // The API server did not answer in time
+    exitUnreachable       = 7 // The API server could not be connected to
+    exitEmptyNamespace    = 8 // Nothing in the namespace to show
+    exitFindings          = 9 // check found violations, or drift found differences
+)
+
+// Reasons reported with an error, beside those from k8s.ErrorReason
+const (
+    reasonUsage             = "Usage"
+    reasonNamespaceNotFound = "NamespaceNotFound"
+    reasonEmptyNamespace    = "EmptyNamespace"
+)
+
+// reasonExitCodes maps the reason for an error to the exit code it gives
+var reasonExitCodes = map[string]int{
+    reasonUsage:             exitUsage,
+    reasonNamespaceNotFound: exitNamespaceNotFound,
+    reasonEmptyNamespace:    exitEmptyNamespace,
+    k8s.ReasonUnauthorized:  exitUnauthorized,
+    k8s.ReasonForbidden:     exitForbidden,
+    k8s.ReasonTimeout:       exitTimeout,
+    k8s.ReasonUnreachable:   exitUnreachable,
+}
+
+// outputFormats are the -o values accepted outside check
+var outputFormats = []string{"text", "json"}
+
+// jsonErrors makes fail write errors as JSON objects, set by -o json
+var jsonErrors bool
+
+// fail reports an error on stderr and exits with the code for its reason. With
+// -o json the error is written as an object scripts can parse, e.g.
+// {"error":{"reason":"Forbidden","message":"...","exitCode":5}}
+func fail(reason string, err error) {
+    code, ok := reasonExitCodes[reason]
+    if !ok {
+        code = exitError
+    }
+
+    if jsonErrors {
+        type errorObject struct {
+            Reason   string `json:"reason"`
+            Message  string `json:"message"`
+            ExitCode int    `json:"exitCode"`
+        }
+        json.NewEncoder(os.Stderr).Encode(map[string]errorObject{
+            "error": {Reason: reason, Message: err.Error(), ExitCode: code},
+        })
+    } else {
+        fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+    }
+    os.Exit(code)
+}
+
+// failRequest reports an error from a request to the cluster, classified by
+// what went wrong
+func failRequest(err error) {
+    var empty *tree.EmptyError
+    if errors.As(err, &empty) {
+        fail(reasonEmptyNamespace, err)
+        return
+    }
+    fail(k8s.ErrorReason(err), err)
+}
+
+// subcommands are the modes selected by the first argument, e.g. `kubectl tree orphans`
+var subcommands = map[string]bool{
+    "orphans": true,
+    "drift":   true,
+    "check":   true,
+}
+
 func main() {
     var kubeconfig *string
+    var rulesPath *string
     var showVersion bool
     var namespace string
     var debug bool
+    var interactive bool
+    var byNode bool
+    var wide bool
+    var metrics bool
+    var events bool
+    var groupBy string
+    var followCrossNamespace bool
+    var contexts string
+    var allContexts bool
+    var manifestPath string
+    var output string
+
+    // Pick off a subcommand before parsing flags
+    command := ""
+    args := os.Args[1:]
+    if len(args) > 0 && subcommands[args[0]] {
+        command = args[0]
+        args = args[1:]
+    }
 
     if home := homedir.HomeDir(); home != "" {
         kubeconfig = flag.String("kubeconfig", filepath.Join(home, ".kube", "config"), "(optional) absolute path to the kubeconfig file")
+        rulesPath = flag.String("config", filepath.Join(home, ".kube", "tree.yaml"), "(optional) path to relationship rules for custom resources and check settings")
     } else {
         kubeconfig = flag.String("kubeconfig", "", "absolute path to the kubeconfig file")
+        rulesPath = flag.String("config", "", "path to relationship rules for custom resources and check settings")
     }
 
     flag.BoolVar(&showVersion, "version", false, "show version information")
     flag.StringVar(&namespace, "n", "", "namespace to show tree for (defaults to current namespace)")
     flag.BoolVar(&debug, "debug", false, "enable debug output")
-    flag.Parse()
+    flag.BoolVar(&interactive, "tui", false, "browse the tree in an interactive terminal UI")
+    flag.BoolVar(&byNode, "by-node", false, "group pods by the node they are scheduled on")
+    flag.BoolVar(&wide, "wide", false, "show container images, resources, ports and probes, and pod QoS, IP and node")
+    flag.BoolVar(&metrics, "metrics", false, "show live CPU and memory usage from the metrics API")
+    flag.BoolVar(&events, "events", false, "show recent Warning events beside the resources they concern")
+    flag.BoolVar(&followCrossNamespace, "follow-cross-namespace", false, "follow ExternalName Services, Ingresses, RoleBindings and ReferenceGrants into other namespaces")
+    flag.StringVar(&contexts, "contexts", "", "comma-separated kubeconfig contexts to build the tree in, one top-level node per cluster")
+    flag.BoolVar(&allContexts, "all-contexts", false, "build the tree in every kubeconfig context")
+    flag.StringVar(&manifestPath, "f", "", "manifest file or directory to compare the namespace against (drift)")
+    flag.StringVar(&output, "o", "text", "output format: "+strings.Join(outputFormats, " or ")+", and for check also sarif or junit; json also writes errors as JSON objects")
+    flag.StringVar(&groupBy, "group-by", "", "group workloads by what manages them: "+strings.Join(tree.GroupByModes, ", "))
+    flag.CommandLine.Parse(args)
+
+    jsonErrors = output == "json"
 
     if showVersion {
         fmt.Printf("kubectl-tree version %s\n", version)
         return
     }
 
+    formats := outputFormats
+    if command == "check" {
+        formats = check.Formats
+    }
+    if !slices.Contains(formats, output) {
+        fail(reasonUsage, fmt.Errorf("unknown -o %q, expected one of: %s", output, strings.Join(formats, ", ")))
+    }
+
+    if groupBy != "" && !slices.Contains(tree.GroupByModes, groupBy) {
+        fail(reasonUsage, fmt.Errorf("unknown -group-by %q, expected one of: %s", groupBy, strings.Join(tree.GroupByModes, ", ")))
+    }
+
+    if (contexts != "" || allContexts) && interactive {
+        fail(reasonUsage, errors.New("-tui shows a single cluster and cannot be combined with -contexts or -all-contexts"))
+    }
+
+    if command == "check" && (contexts != "" || allContexts) {
+        fail(reasonUsage, errors.New("check runs against a single cluster and cannot be combined with -contexts or -all-contexts"))
+    }
+
     // Get current namespace if not specified
     if namespace == "" {
         if ns, err := util.GetCurrentNamespace(*kubeconfig); err == nil {
@@ -45,24 +185,151 @@ func main() {
         }
     }
 
+    // Load relationship rules, which draw edges like the built-in resolvers
+    rules, err := k8s.LoadRules(*rulesPath)
+    if err != nil {
+        fail(k8s.ReasonUnknown, err)
+    }
+    k8s.RegisterResolver("config", rules)
+
+    options := tree.Options{
+        Debug:   debug,
+        Wide:    wide,
+        Metrics: metrics,
+        Events:  events,
+        Rules:   rules,
+        GroupBy: groupBy,
+
+        FollowCrossNamespace: followCrossNamespace,
+    }
+    build := tree.BuildFunc((*tree.Builder).BuildTree)
+    switch {
+    case command == "orphans":
+        build = (*tree.Builder).BuildOrphanTree
+    case command == "drift":
+        if manifestPath == "" {
+            fail(reasonUsage, errors.New("drift needs manifests to compare against, e.g. drift -f ./manifests"))
+        }
+        objects, err := k8s.LoadManifests(manifestPath)
+        if err != nil {
+            fail(k8s.ReasonUnknown, err)
+        }
+        build = func(b *tree.Builder, namespace string) (*tree.Resource, error) {
+            manifests, err := k8s.ManifestResources(objects, namespace)
+            if err != nil {
+                return nil, err
+            }
+            return b.BuildDriftTree(namespace, manifests)
+        }
+    case byNode:
+        build = (*tree.Builder).BuildNodeTree
+    }
+
+    // Build the tree in several clusters at once
+    if contexts != "" || allContexts {
+        var names []string
+        if allContexts {
+            names, err = util.GetContexts(*kubeconfig)
+            if err != nil {
+                fail(k8s.ReasonUnknown, err)
+            }
+        } else {
+            for _, name := range strings.Split(contexts, ",") {
+                if name = strings.TrimSpace(name); name != "" {
+                    names = append(names, name)
+                }
+            }
+        }
+
+        root, err := tree.BuildClusterTree(*kubeconfig, names, namespace, options, build)
+        printTree(root, output)
+        if command == "drift" {
+            // Drift in any cluster fails the command like a single cluster's
+            for _, cluster := range root.Children {
+                if len(cluster.Children) > 0 {
+                    os.Exit(exitFindings)
+                }
+            }
+        }
+        // A cluster that could not be built fails the command once the
+        // others have been shown
+        if err != nil {
+            failRequest(err)
+        }
+        return
+    }
+
     // Create kubernetes client
     client, err := k8s.NewClient(*kubeconfig)
     if err != nil {
-        fmt.Printf("Error: %v\n", err)
-        os.Exit(1)
+        fail(k8s.ReasonUnknown, err)
     }
 
-    // Check if namespace exists
+    // Check if namespace exists; this is also the first request, so an
+    // unreachable cluster or bad credentials show up here
     if err := client.NamespaceExists(namespace); err != nil {
-        fmt.Printf("Error: namespace '%s' not found\n", namespace)
-        os.Exit(1)
+        if k8s.ErrorReason(err) == k8s.ReasonNotFound {
+            fail(reasonNamespaceNotFound, fmt.Errorf("namespace %q not found", namespace))
+        }
+        failRequest(err)
+    }
+
+    // Evaluate the check rules against the tree, failing if any error or
+    // warning rule is broken
+    if command == "check" {
+        config, err := check.LoadConfig(*rulesPath)
+        if err != nil {
+            fail(k8s.ReasonUnknown, err)
+        }
+        pdbs, err := client.GetPodDisruptionBudgets(namespace)
+        if err != nil {
+            failRequest(err)
+        }
+        root, err := tree.NewBuilder(client, options).BuildTree(namespace)
+        if err != nil {
+            failRequest(err)
+        }
+
+        violations := check.Run(root, pdbs.Items, config)
+        if err := check.Write(os.Stdout, output, namespace, config.EnabledRules(), violations); err != nil {
+            fail(k8s.ReasonUnknown, err)
+        }
+        if check.Failing(violations) {
+            os.Exit(exitFindings)
+        }
+        return
     }
 
     // Get the tree
-    root, err := tree.NewBuilder(client, debug).BuildTree(namespace)
+    root, err := build(tree.NewBuilder(client, options), namespace)
     if err != nil {
-        fmt.Printf("Error building resource tree: %v\n", os.Stderr)
-        os.Exit(1)
+        failRequest(err)
+    }
+
+    if interactive {
+        if err := tui.Run(root, client, namespace); err != nil {
+            fail(k8s.ReasonUnknown, err)
+        }
+        return
+    }
+
+    printTree(root, output)
+
+    // Differences from the manifests fail the command like check violations
+    if command == "drift" && root != nil {
+        os.Exit(exitFindings)
+    }
+}
+
+// printTree prints the tree, or with -o json writes it as a JSON object
+func printTree(root *tree.Resource, output string) {
+    if output == "json" {
+        encoder := json.NewEncoder(os.Stdout)
+        encoder.SetIndent("", "  ")
+        if err := encoder.Encode(root); err != nil {
+            fail(k8s.ReasonUnknown, err)
+        }
+        return
     }
 
     // Create printer with color support
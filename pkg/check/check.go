@@ -0,0 +1,364 @@
+package check
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"kubectl-tree/pkg/k8s"
+	"kubectl-tree/pkg/tree"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/yaml"
+)
+
+// Defaults for the thresholds in Config
+const (
+	defaultMaxRestarts        = 5
+	defaultMaxSecretConsumers = 3
+)
+
+// Levels, as used by SARIF
+const (
+	LevelError   = "error"
+	LevelWarning = "warning"
+	LevelNote    = "note"
+)
+
+// Rule is a check run against the tree of a namespace
+type Rule struct {
+	ID          string
+	Level       string
+	Description string
+
+	run func(c *checker)
+}
+
+// Rules lists every check, in the order they run
+var Rules = []*Rule{
+	{ID: "missing-pdb", Level: LevelWarning, Description: "Deployment has no PodDisruptionBudget covering its pods", run: checkMissingPDB},
+	{ID: "no-service", Level: LevelNote, Description: "Workload has no Service selecting its pods", run: checkNoService},
+	{ID: "missing-limits", Level: LevelWarning, Description: "Container has no CPU or memory limit", run: checkMissingLimits},
+	{ID: "latest-tag", Level: LevelWarning, Description: "Container image uses the latest tag or no tag", run: checkLatestTag},
+	{ID: "shared-secret", Level: LevelNote, Description: "Secret is used by many workloads", run: checkSharedSecret},
+	{ID: "unbound-pvc", Level: LevelError, Description: "PersistentVolumeClaim is not bound", run: checkUnboundPVC},
+	{ID: "pod-restarts", Level: LevelError, Description: "Pod has restarted too many times", run: checkPodRestarts},
+}
+
+// Violation is one object breaking a rule
+type Violation struct {
+	Rule    *Rule
+	Kind    string
+	Name    string
+	Message string
+}
+
+// Config selects the rules to run and sets their thresholds. It is read from
+// the checks section of the config file, e.g.
+//
+//	checks:
+//	  disabled: [no-service]
+//	  maxRestarts: 10
+type Config struct {
+	Enabled            []string `json:"enabled,omitempty"`  // Run only these rules; all when empty
+	Disabled           []string `json:"disabled,omitempty"` // Never run these rules
+	MaxRestarts        int      `json:"maxRestarts,omitempty"`
+	MaxSecretConsumers int      `json:"maxSecretConsumers,omitempty"`
+}
+
+// LoadConfig reads the checks section of the config file. A missing file or
+// section gives the defaults.
+func LoadConfig(path string) (*Config, error) {
+	var file struct {
+		Rules  json.RawMessage `json:"rules,omitempty"` // Read by k8s.LoadRules
+		Checks Config          `json:"checks"`
+	}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		switch {
+		case os.IsNotExist(err):
+		case err != nil:
+			return nil, fmt.Errorf("error reading config: %v", err)
+		default:
+			if err := yaml.UnmarshalStrict(data, &file); err != nil {
+				return nil, fmt.Errorf("error parsing checks in %s: %v", path, err)
+			}
+		}
+	}
+
+	config := &file.Checks
+	for _, id := range append(append([]string{}, config.Enabled...), config.Disabled...) {
+		if findRule(id) == nil {
+			return nil, fmt.Errorf("unknown check %q in %s", id, path)
+		}
+	}
+	if config.MaxRestarts == 0 {
+		config.MaxRestarts = defaultMaxRestarts
+	}
+	if config.MaxSecretConsumers == 0 {
+		config.MaxSecretConsumers = defaultMaxSecretConsumers
+	}
+	return config, nil
+}
+
+// EnabledRules returns the rules the config selects
+func (config *Config) EnabledRules() []*Rule {
+	var rules []*Rule
+	for _, rule := range Rules {
+		if len(config.Enabled) > 0 && !slices.Contains(config.Enabled, rule.ID) {
+			continue
+		}
+		if slices.Contains(config.Disabled, rule.ID) {
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// findRule returns the rule with the given ID, or nil
+func findRule(id string) *Rule {
+	for _, rule := range Rules {
+		if rule.ID == id {
+			return rule
+		}
+	}
+	return nil
+}
+
+// checker holds what the rules look at while they run
+type checker struct {
+	root       *tree.Resource
+	pdbs       []policyv1.PodDisruptionBudget
+	config     *Config
+	rule       *Rule
+	violations []Violation
+}
+
+// Run evaluates the enabled rules against a namespace tree built by
+// tree.Builder.BuildTree, with the namespace's PodDisruptionBudgets. Violations
+// come in rule order, then in tree order.
+func Run(root *tree.Resource, pdbs []policyv1.PodDisruptionBudget, config *Config) []Violation {
+	if root == nil {
+		return nil
+	}
+
+	c := &checker{root: root, pdbs: pdbs, config: config}
+	for _, rule := range config.EnabledRules() {
+		c.rule = rule
+		rule.run(c)
+	}
+	return c.violations
+}
+
+// Failing reports whether any violation is of an error or warning rule. Notes
+// are informational and do not fail a check.
+func Failing(violations []Violation) bool {
+	for _, v := range violations {
+		if v.Rule.Level != LevelNote {
+			return true
+		}
+	}
+	return false
+}
+
+// report records a violation of the running rule
+func (c *checker) report(kind, name, format string, args ...interface{}) {
+	c.violations = append(c.violations, Violation{
+		Rule:    c.rule,
+		Kind:    kind,
+		Name:    name,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// workloadKinds are the kinds whose nodes carry a pod template
+var workloadKinds = map[string]bool{
+	"Deployment":            true,
+	"StatefulSet":           true,
+	"DaemonSet":             true,
+	"Job":                   true,
+	"CronJob":               true,
+	"ReplicationController": true,
+}
+
+// walk calls fn for every node of a kind in the tree, skipping objects from
+// other namespaces
+func (c *checker) walk(kinds map[string]bool, fn func(node *tree.Resource, obj metav1.Object)) {
+	var visit func(node *tree.Resource)
+	visit = func(node *tree.Resource) {
+		if obj, ok := node.Object.(metav1.Object); ok && kinds[node.Kind] && node.Namespace == "" {
+			fn(node, obj)
+		}
+		for _, child := range node.Children {
+			visit(child)
+		}
+	}
+	visit(c.root)
+}
+
+// walkWorkloads calls fn for every workload in the tree that no controller
+// created, so a CronJob's Jobs are not checked again on their own
+func (c *checker) walkWorkloads(fn func(node *tree.Resource, obj metav1.Object)) {
+	c.walk(workloadKinds, func(node *tree.Resource, obj metav1.Object) {
+		if metav1.GetControllerOf(obj) == nil {
+			fn(node, obj)
+		}
+	})
+}
+
+// hasChild reports whether a node has a direct child of a kind from its own
+// namespace, added through an edge of the given type
+func hasChild(node *tree.Resource, kind string, edge k8s.EdgeType) bool {
+	for _, child := range node.Children {
+		if child.Kind == kind && child.Namespace == "" && child.Edge == edge {
+			return true
+		}
+	}
+	return false
+}
+
+// checkMissingPDB reports Deployments whose pods no PodDisruptionBudget selects
+func checkMissingPDB(c *checker) {
+	c.walk(map[string]bool{"Deployment": true}, func(node *tree.Resource, obj metav1.Object) {
+		podLabels := labels.Set(k8s.PodTemplateOf(obj).Labels)
+		for i := range c.pdbs {
+			selector, err := metav1.LabelSelectorAsSelector(c.pdbs[i].Spec.Selector)
+			if err == nil && selector.Matches(podLabels) {
+				return
+			}
+		}
+		c.report(node.Kind, node.Name, "no PodDisruptionBudget selects its pods")
+	})
+}
+
+// checkNoService reports long-running workloads without a Service selecting
+// their pods in the tree beneath them. A governing or guessed Service does
+// not count, as it may not route to the pods.
+func checkNoService(c *checker) {
+	kinds := map[string]bool{"Deployment": true, "StatefulSet": true, "DaemonSet": true, "ReplicationController": true}
+	c.walk(kinds, func(node *tree.Resource, obj metav1.Object) {
+		if !hasChild(node, "Service", k8s.EdgeSelects) {
+			c.report(node.Kind, node.Name, "no Service selects its pods")
+		}
+	})
+}
+
+// checkMissingLimits reports containers without a CPU or memory limit
+func checkMissingLimits(c *checker) {
+	c.walkWorkloads(func(node *tree.Resource, obj metav1.Object) {
+		for _, container := range containersOf(obj) {
+			var missing []string
+			if _, ok := container.Resources.Limits[corev1.ResourceCPU]; !ok {
+				missing = append(missing, "CPU")
+			}
+			if _, ok := container.Resources.Limits[corev1.ResourceMemory]; !ok {
+				missing = append(missing, "memory")
+			}
+			if len(missing) > 0 {
+				c.report(node.Kind, node.Name, "container %s has no %s limit", container.Name, strings.Join(missing, " or "))
+			}
+		}
+	})
+}
+
+// containersOf returns the init containers and containers of a workload's pod template
+func containersOf(workload metav1.Object) []corev1.Container {
+	template := k8s.PodTemplateOf(workload)
+	if template == nil {
+		return nil
+	}
+	containers := make([]corev1.Container, 0, len(template.Spec.InitContainers)+len(template.Spec.Containers))
+	containers = append(containers, template.Spec.InitContainers...)
+	return append(containers, template.Spec.Containers...)
+}
+
+// checkLatestTag reports containers whose image is not pinned to a tag or digest
+func checkLatestTag(c *checker) {
+	c.walkWorkloads(func(node *tree.Resource, obj metav1.Object) {
+		for _, container := range containersOf(obj) {
+			if tag := imageTag(container.Image); tag == "" || tag == "latest" {
+				c.report(node.Kind, node.Name, "container %s uses image %s, which is not pinned to a version", container.Name, container.Image)
+			}
+		}
+	})
+}
+
+// imageTag returns the tag of an image reference, or "@" for one pinned by
+// digest, or "" if it has neither
+func imageTag(image string) string {
+	if strings.Contains(image, "@") {
+		return "@"
+	}
+	name := image[strings.LastIndex(image, "/")+1:]
+	if colon := strings.LastIndex(name, ":"); colon >= 0 {
+		return name[colon+1:]
+	}
+	return ""
+}
+
+// checkSharedSecret reports Secrets that more workloads use than the config allows
+func checkSharedSecret(c *checker) {
+	consumers := make(map[string]map[string]bool)
+	c.walkWorkloads(func(node *tree.Resource, obj metav1.Object) {
+		for _, child := range node.Children {
+			if child.Kind != "Secret" || child.Namespace != "" {
+				continue
+			}
+			if consumers[child.Name] == nil {
+				consumers[child.Name] = make(map[string]bool)
+			}
+			consumers[child.Name][node.Kind+"/"+node.Name] = true
+		}
+	})
+
+	names := make([]string, 0, len(consumers))
+	for name := range consumers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if len(consumers[name]) > c.config.MaxSecretConsumers {
+			c.report("Secret", name, "used by %d workloads, more than %d", len(consumers[name]), c.config.MaxSecretConsumers)
+		}
+	}
+}
+
+// checkUnboundPVC reports PersistentVolumeClaims that are not bound to a volume
+func checkUnboundPVC(c *checker) {
+	seen := make(map[string]bool)
+	c.walk(map[string]bool{"PersistentVolumeClaim": true}, func(node *tree.Resource, obj metav1.Object) {
+		pvc, ok := obj.(*corev1.PersistentVolumeClaim)
+		if !ok || seen[pvc.Name] || pvc.Status.Phase == corev1.ClaimBound {
+			return
+		}
+		seen[pvc.Name] = true
+		c.report(node.Kind, pvc.Name, "claim is %s", pvc.Status.Phase)
+	})
+}
+
+// checkPodRestarts reports pods whose containers restarted more often than the config allows
+func checkPodRestarts(c *checker) {
+	c.walk(map[string]bool{"Pod": true}, func(node *tree.Resource, obj metav1.Object) {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok {
+			return
+		}
+		var restarts int32
+		for _, status := range pod.Status.InitContainerStatuses {
+			restarts += status.RestartCount
+		}
+		for _, status := range pod.Status.ContainerStatuses {
+			restarts += status.RestartCount
+		}
+		if int(restarts) > c.config.MaxRestarts {
+			c.report(node.Kind, pod.Name, "restarted %d times, more than %d", restarts, c.config.MaxRestarts)
+		}
+	})
+}
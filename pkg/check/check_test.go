@@ -0,0 +1,308 @@
+package check
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"kubectl-tree/pkg/k8s"
+	"kubectl-tree/pkg/tree"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// limited is a container with an image pinned to a tag and both limits set
+var limited = corev1.Container{
+	Name:  "app",
+	Image: "web:1.0",
+	Resources: corev1.ResourceRequirements{Limits: corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("1"),
+		corev1.ResourceMemory: resource.MustParse("1Gi"),
+	}},
+}
+
+// deploymentNode returns a Deployment node whose pods carry app=name
+func deploymentNode(name string, containers []corev1.Container, children ...*tree.Resource) *tree.Resource {
+	dep := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": name}},
+			Spec:       corev1.PodSpec{Containers: containers},
+		}},
+	}
+	return &tree.Resource{Kind: "Deployment", Name: name, Object: dep, Children: children}
+}
+
+// serviceNode returns a Service node reached through an edge of the given type
+func serviceNode(name string, edge k8s.EdgeType) *tree.Resource {
+	return &tree.Resource{Kind: "Service", Name: name, Object: &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: name}}, Edge: edge}
+}
+
+// secretNode returns a Secret node used by a workload
+func secretNode(name string) *tree.Resource {
+	return &tree.Resource{Kind: "Secret", Name: name, Object: &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name}}, Edge: k8s.EdgeMounts}
+}
+
+// namespaceNode returns the root of a namespace tree
+func namespaceNode(children ...*tree.Resource) *tree.Resource {
+	return &tree.Resource{Kind: "Namespace", Name: "ns", Children: children}
+}
+
+func TestRules(t *testing.T) {
+	pdb := policyv1.PodDisruptionBudget{Spec: policyv1.PodDisruptionBudgetSpec{
+		Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+	}}
+	pvc := func(name string, phase corev1.PersistentVolumeClaimPhase) *tree.Resource {
+		return &tree.Resource{Kind: "PersistentVolumeClaim", Name: name, Object: &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status:     corev1.PersistentVolumeClaimStatus{Phase: phase},
+		}}
+	}
+	pod := func(name string, restarts int32) *tree.Resource {
+		return &tree.Resource{Kind: "Pod", Name: name, Object: &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: corev1.PodStatus{
+				InitContainerStatuses: []corev1.ContainerStatus{{RestartCount: 1}},
+				ContainerStatuses:     []corev1.ContainerStatus{{RestartCount: restarts}},
+			},
+		}}
+	}
+	foreign := serviceNode("web", k8s.EdgeSelects)
+	foreign.Namespace = "other"
+	unlimited := corev1.Container{Name: "app", Image: "web:1.0"}
+
+	tests := []struct {
+		rule string
+		root *tree.Resource
+		pdbs []policyv1.PodDisruptionBudget
+		want []string
+	}{
+		{
+			rule: "missing-pdb",
+			root: namespaceNode(deploymentNode("web", []corev1.Container{limited}), deploymentNode("api", []corev1.Container{limited})),
+			pdbs: []policyv1.PodDisruptionBudget{pdb},
+			want: []string{"Deployment/api: no PodDisruptionBudget selects its pods"},
+		},
+		{
+			rule: "no-service",
+			root: namespaceNode(
+				deploymentNode("selected", []corev1.Container{limited}, serviceNode("selected", k8s.EdgeSelects)),
+				deploymentNode("guessed", []corev1.Container{limited}, serviceNode("guessed", k8s.EdgeGuess)),
+				deploymentNode("governed", []corev1.Container{limited}, serviceNode("governed", k8s.EdgeGoverns)),
+				deploymentNode("foreign", []corev1.Container{limited}, foreign),
+				deploymentNode("none", []corev1.Container{limited}),
+			),
+			want: []string{
+				"Deployment/guessed: no Service selects its pods",
+				"Deployment/governed: no Service selects its pods",
+				"Deployment/foreign: no Service selects its pods",
+				"Deployment/none: no Service selects its pods",
+			},
+		},
+		{
+			rule: "missing-limits",
+			root: namespaceNode(
+				deploymentNode("web", []corev1.Container{limited}),
+				deploymentNode("api", []corev1.Container{unlimited}),
+			),
+			want: []string{"Deployment/api: container app has no CPU or memory limit"},
+		},
+		{
+			rule: "latest-tag",
+			root: namespaceNode(
+				deploymentNode("tagged", []corev1.Container{{Name: "app", Image: "registry:5000/web:1.0"}}),
+				deploymentNode("digest", []corev1.Container{{Name: "app", Image: "web@sha256:abc"}}),
+				deploymentNode("latest", []corev1.Container{{Name: "app", Image: "web:latest"}}),
+				deploymentNode("untagged", []corev1.Container{{Name: "app", Image: "registry:5000/web"}}),
+			),
+			want: []string{
+				"Deployment/latest: container app uses image web:latest, which is not pinned to a version",
+				"Deployment/untagged: container app uses image registry:5000/web, which is not pinned to a version",
+			},
+		},
+		{
+			rule: "shared-secret",
+			root: namespaceNode(
+				deploymentNode("a", []corev1.Container{limited}, secretNode("shared"), secretNode("few")),
+				deploymentNode("b", []corev1.Container{limited}, secretNode("shared"), secretNode("few")),
+				deploymentNode("c", []corev1.Container{limited}, secretNode("shared"), secretNode("few")),
+				deploymentNode("d", []corev1.Container{limited}, secretNode("shared")),
+			),
+			want: []string{"Secret/shared: used by 4 workloads, more than 3"},
+		},
+		{
+			rule: "unbound-pvc",
+			root: namespaceNode(
+				deploymentNode("a", []corev1.Container{limited}, pvc("data", corev1.ClaimPending), pvc("logs", corev1.ClaimBound)),
+				deploymentNode("b", []corev1.Container{limited}, pvc("data", corev1.ClaimPending)),
+			),
+			want: []string{"PersistentVolumeClaim/data: claim is Pending"},
+		},
+		{
+			rule: "pod-restarts",
+			root: namespaceNode(deploymentNode("web", []corev1.Container{limited}, pod("web-1", 4), pod("web-2", 5))),
+			want: []string{"Pod/web-2: restarted 6 times, more than 5"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			config := &Config{Enabled: []string{tt.rule}, MaxRestarts: defaultMaxRestarts, MaxSecretConsumers: defaultMaxSecretConsumers}
+			var got []string
+			for _, v := range Run(tt.root, tt.pdbs, config) {
+				if v.Rule.ID != tt.rule {
+					t.Errorf("violation of %s while running only %s", v.Rule.ID, tt.rule)
+				}
+				got = append(got, v.Kind+"/"+v.Name+": "+v.Message)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Run() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunSkipsControlledWorkloads(t *testing.T) {
+	controller := true
+	job := &tree.Resource{Kind: "Job", Name: "backup-1", Object: &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "backup-1",
+			OwnerReferences: []metav1.OwnerReference{{Kind: "CronJob", Name: "backup", Controller: &controller}},
+		},
+		Spec: batchv1.JobSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "backup", Image: "backup:latest"}},
+		}}},
+	}}
+	root := namespaceNode(job)
+	config := &Config{Enabled: []string{"latest-tag"}}
+	if got := Run(root, nil, config); len(got) != 0 {
+		t.Errorf("Run() = %+v, want controlled workloads skipped", got)
+	}
+	if got := Run(nil, nil, config); got != nil {
+		t.Errorf("Run(nil) = %+v, want nil", got)
+	}
+}
+
+func TestFailing(t *testing.T) {
+	violation := func(rule string) Violation {
+		return Violation{Rule: findRule(rule), Kind: "Deployment", Name: "web"}
+	}
+
+	tests := []struct {
+		name       string
+		violations []Violation
+		want       bool
+	}{
+		{name: "none", want: false},
+		{name: "notes only", violations: []Violation{violation("no-service"), violation("shared-secret")}, want: false},
+		{name: "warning", violations: []Violation{violation("no-service"), violation("latest-tag")}, want: true},
+		{name: "error", violations: []Violation{violation("pod-restarts")}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Failing(tt.violations); got != tt.want {
+				t.Errorf("Failing() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    string // Written to the config file; "-" for no file
+		wantRules []string
+		wantErr   string
+	}{
+		{
+			name:      "no file",
+			config:    "-",
+			wantRules: []string{"missing-pdb", "no-service", "missing-limits", "latest-tag", "shared-secret", "unbound-pvc", "pod-restarts"},
+		},
+		{
+			name:      "rules section only",
+			config:    "rules:\n- from: apps/Deployment\n  path: spec\n  to: Secret\n",
+			wantRules: []string{"missing-pdb", "no-service", "missing-limits", "latest-tag", "shared-secret", "unbound-pvc", "pod-restarts"},
+		},
+		{
+			name:      "enabled",
+			config:    "checks:\n  enabled: [pod-restarts, latest-tag]\n",
+			wantRules: []string{"latest-tag", "pod-restarts"},
+		},
+		{
+			name:      "disabled",
+			config:    "checks:\n  disabled: [no-service, missing-limits, shared-secret]\n",
+			wantRules: []string{"missing-pdb", "latest-tag", "unbound-pvc", "pod-restarts"},
+		},
+		{
+			name:      "disabled wins over enabled",
+			config:    "checks:\n  enabled: [latest-tag, pod-restarts]\n  disabled: [latest-tag]\n",
+			wantRules: []string{"pod-restarts"},
+		},
+		{
+			name:    "unknown rule",
+			config:  "checks:\n  disabled: [no-such-rule]\n",
+			wantErr: `unknown check "no-such-rule"`,
+		},
+		{
+			name:    "unknown field",
+			config:  "checks:\n  maxRestart: 3\n",
+			wantErr: "error parsing checks",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if tt.config != "-" {
+				if err := os.WriteFile(path, []byte(tt.config), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			config, err := LoadConfig(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadConfig() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+
+			var got []string
+			for _, rule := range config.EnabledRules() {
+				got = append(got, rule.ID)
+			}
+			if !reflect.DeepEqual(got, tt.wantRules) {
+				t.Errorf("EnabledRules() = %v, want %v", got, tt.wantRules)
+			}
+			if config.MaxRestarts != defaultMaxRestarts || config.MaxSecretConsumers != defaultMaxSecretConsumers {
+				t.Errorf("thresholds = %d, %d, want the defaults", config.MaxRestarts, config.MaxSecretConsumers)
+			}
+		})
+	}
+}
+
+func TestLoadConfigThresholds(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("checks:\n  maxRestarts: 10\n  maxSecretConsumers: 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.MaxRestarts != 10 || config.MaxSecretConsumers != 1 {
+		t.Errorf("thresholds = %d, %d, want 10, 1", config.MaxRestarts, config.MaxSecretConsumers)
+	}
+}
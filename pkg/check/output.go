@@ -0,0 +1,226 @@
+package check
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Output formats
+const (
+	FormatText  = "text"
+	FormatJSON  = "json"
+	FormatSARIF = "sarif"
+	FormatJUnit = "junit"
+)
+
+// Formats lists the accepted output formats
+var Formats = []string{FormatText, FormatJSON, FormatSARIF, FormatJUnit}
+
+// Write prints the violations found in a namespace in the given format. The
+// rules that ran are listed in SARIF and JUnit output, so rules that passed
+// are visible too.
+func Write(w io.Writer, format, namespace string, rules []*Rule, violations []Violation) error {
+	switch format {
+	case FormatJSON:
+		return writeJSON(w, namespace, violations)
+	case FormatSARIF:
+		return writeSARIF(w, namespace, rules, violations)
+	case FormatJUnit:
+		return writeJUnit(w, namespace, rules, violations)
+	default:
+		return writeText(w, namespace, violations)
+	}
+}
+
+// writeText prints a line per violation followed by a count
+func writeText(w io.Writer, namespace string, violations []Violation) error {
+	if len(violations) == 0 {
+		_, err := fmt.Fprintf(w, "No violations found in %s namespace.\n", namespace)
+		return err
+	}
+	for _, v := range violations {
+		if _, err := fmt.Fprintf(w, "%-7s %-14s %s/%s: %s\n", v.Rule.Level, v.Rule.ID, v.Kind, v.Name, v.Message); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "\n%d violations found in %s namespace.\n", len(violations), namespace)
+	return err
+}
+
+// jsonViolation is a violation as written by -o json
+type jsonViolation struct {
+	Rule    string `json:"rule"`
+	Level   string `json:"level"`
+	Kind    string `json:"kind"`
+	Name    string `json:"name"`
+	Message string `json:"message"`
+}
+
+// writeJSON prints the violations as a JSON object for scripts
+func writeJSON(w io.Writer, namespace string, violations []Violation) error {
+	result := struct {
+		Namespace  string          `json:"namespace"`
+		Violations []jsonViolation `json:"violations"`
+	}{Namespace: namespace, Violations: make([]jsonViolation, 0, len(violations))}
+	for _, v := range violations {
+		result.Violations = append(result.Violations, jsonViolation{
+			Rule:    v.Rule.ID,
+			Level:   v.Rule.Level,
+			Kind:    v.Kind,
+			Name:    v.Name,
+			Message: v.Message,
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+// SARIF 2.1.0 documents, with only the fields this tool fills in
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string       `json:"id"`
+	ShortDescription     sarifMessage `json:"shortDescription"`
+	DefaultConfiguration struct {
+		Level string `json:"level"`
+	} `json:"defaultConfiguration"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations"`
+}
+
+type sarifLogicalLocation struct {
+	Name               string `json:"name"`
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+// writeSARIF prints the violations as a SARIF log, locating each by its
+// namespace, kind and name since there is no source file to point at
+func writeSARIF(w io.Writer, namespace string, rules []*Rule, violations []Violation) error {
+	run := sarifRun{
+		Tool:    sarifTool{Driver: sarifDriver{Name: "kubectl-tree", Rules: make([]sarifRule, 0, len(rules))}},
+		Results: make([]sarifResult, 0, len(violations)),
+	}
+	for _, rule := range rules {
+		r := sarifRule{ID: rule.ID, ShortDescription: sarifMessage{Text: rule.Description}}
+		r.DefaultConfiguration.Level = rule.Level
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, r)
+	}
+	for _, v := range violations {
+		run.Results = append(run.Results, sarifResult{
+			RuleID:  v.Rule.ID,
+			Level:   v.Rule.Level,
+			Message: sarifMessage{Text: v.Kind + "/" + v.Name + ": " + v.Message},
+			Locations: []sarifLocation{{LogicalLocations: []sarifLogicalLocation{{
+				Name:               v.Name,
+				FullyQualifiedName: namespace + "/" + v.Kind + "/" + v.Name,
+				Kind:               "resource",
+			}}}},
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs:    []sarifRun{run},
+	})
+}
+
+// JUnit XML documents, with a test case per rule
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnit prints a JUnit report with a test case per rule that ran, failed
+// with the list of its violations if it has any
+func writeJUnit(w io.Writer, namespace string, rules []*Rule, violations []Violation) error {
+	suite := junitTestSuite{Name: "kubectl-tree check " + namespace, Tests: len(rules)}
+	for _, rule := range rules {
+		testCase := junitTestCase{ClassName: namespace, Name: rule.ID}
+
+		var lines []string
+		for _, v := range violations {
+			if v.Rule == rule {
+				lines = append(lines, v.Kind+"/"+v.Name+": "+v.Message)
+			}
+		}
+		if len(lines) > 0 {
+			testCase.Failure = &junitFailure{
+				Message: fmt.Sprintf("%d violations: %s", len(lines), rule.Description),
+				Type:    rule.Level,
+				Text:    strings.Join(lines, "\n"),
+			}
+			suite.Failures++
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(junitTestSuites{Suites: []junitTestSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,157 @@
+package check
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+// testViolations returns two rules that ran and a violation of the first
+func testViolations() ([]*Rule, []Violation) {
+	rules := []*Rule{findRule("latest-tag"), findRule("pod-restarts")}
+	violations := []Violation{
+		{Rule: rules[0], Kind: "Deployment", Name: "web", Message: "container app uses image web, which is not pinned to a version"},
+		{Rule: rules[0], Kind: "Deployment", Name: "api", Message: "container app uses image api:latest, which is not pinned to a version"},
+	}
+	return rules, violations
+}
+
+func TestWriteSARIF(t *testing.T) {
+	tests := []struct {
+		name        string
+		violations  bool
+		wantResults []string
+	}{
+		{name: "violations", violations: true, wantResults: []string{"latest-tag ns/Deployment/web", "latest-tag ns/Deployment/api"}},
+		{name: "clean"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, violations := testViolations()
+			if !tt.violations {
+				violations = nil
+			}
+			var out bytes.Buffer
+			if err := Write(&out, FormatSARIF, "ns", rules, violations); err != nil {
+				t.Fatal(err)
+			}
+
+			var log sarifLog
+			if err := json.Unmarshal(out.Bytes(), &log); err != nil {
+				t.Fatalf("output is not JSON: %v\n%s", err, out.String())
+			}
+			if log.Version != "2.1.0" || len(log.Runs) != 1 {
+				t.Fatalf("version %q with %d runs, want 2.1.0 with 1", log.Version, len(log.Runs))
+			}
+			run := log.Runs[0]
+
+			var ruleIDs []string
+			for _, rule := range run.Tool.Driver.Rules {
+				ruleIDs = append(ruleIDs, rule.ID+"/"+rule.DefaultConfiguration.Level)
+			}
+			if want := []string{"latest-tag/warning", "pod-restarts/error"}; strings.Join(ruleIDs, ",") != strings.Join(want, ",") {
+				t.Errorf("rules = %v, want %v", ruleIDs, want)
+			}
+
+			var results []string
+			for _, result := range run.Results {
+				results = append(results, result.RuleID+" "+result.Locations[0].LogicalLocations[0].FullyQualifiedName)
+			}
+			if strings.Join(results, ",") != strings.Join(tt.wantResults, ",") {
+				t.Errorf("results = %v, want %v", results, tt.wantResults)
+			}
+			// An empty list rather than null, which some SARIF consumers reject
+			if !strings.Contains(out.String(), `"results": [`) {
+				t.Errorf("results missing from output:\n%s", out.String())
+			}
+		})
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	tests := []struct {
+		name         string
+		violations   bool
+		wantFailures int
+		wantFailed   map[string]string // Test case name to failure text
+	}{
+		{
+			name:         "violations",
+			violations:   true,
+			wantFailures: 1,
+			wantFailed: map[string]string{
+				"latest-tag": "Deployment/web: container app uses image web, which is not pinned to a version\n" +
+					"Deployment/api: container app uses image api:latest, which is not pinned to a version",
+			},
+		},
+		{name: "clean", wantFailed: map[string]string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, violations := testViolations()
+			if !tt.violations {
+				violations = nil
+			}
+			var out bytes.Buffer
+			if err := Write(&out, FormatJUnit, "ns", rules, violations); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(out.String(), xml.Header) {
+				t.Errorf("output does not start with the XML header:\n%s", out.String())
+			}
+
+			var suites junitTestSuites
+			if err := xml.Unmarshal(out.Bytes(), &suites); err != nil {
+				t.Fatalf("output is not XML: %v\n%s", err, out.String())
+			}
+			if len(suites.Suites) != 1 {
+				t.Fatalf("%d suites, want 1", len(suites.Suites))
+			}
+			suite := suites.Suites[0]
+			if suite.Tests != 2 || len(suite.Cases) != 2 || suite.Failures != tt.wantFailures {
+				t.Errorf("suite has tests=%d cases=%d failures=%d, want 2, 2, %d", suite.Tests, len(suite.Cases), suite.Failures, tt.wantFailures)
+			}
+
+			failed := make(map[string]string)
+			for _, testCase := range suite.Cases {
+				if testCase.ClassName != "ns" {
+					t.Errorf("case %s has class %q, want ns", testCase.Name, testCase.ClassName)
+				}
+				if testCase.Failure != nil {
+					failed[testCase.Name] = testCase.Failure.Text
+				}
+			}
+			if len(failed) != len(tt.wantFailed) {
+				t.Errorf("failed cases = %v, want %v", failed, tt.wantFailed)
+			}
+			for name, text := range tt.wantFailed {
+				if failed[name] != text {
+					t.Errorf("failure of %s = %q, want %q", name, failed[name], text)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	rules, violations := testViolations()
+	var out bytes.Buffer
+	if err := Write(&out, FormatText, "ns", rules, violations); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "latest-tag") || !strings.Contains(out.String(), "2 violations found in ns namespace.") {
+		t.Errorf("unexpected text output:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := Write(&out, FormatText, "ns", rules, nil); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "No violations found in ns namespace.\n" {
+		t.Errorf("unexpected text output:\n%s", out.String())
+	}
+}
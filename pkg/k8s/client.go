@@ -7,44 +7,112 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
 	"k8s.io/client-go/tools/clientcmd"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsclient "k8s.io/metrics/pkg/client/clientset/versioned"
 )
 
 // Client wraps the Kubernetes clientset
 type Client struct {
 	clientset *kubernetes.Clientset
+	metrics   *metricsclient.Clientset
+	dynamic   dynamic.Interface // For custom resources named by relationship rules
+	mapper    meta.RESTMapper
 }
 
 // Resources holds all the resources fetched from the cluster
 type Resources struct {
-	Services     *corev1.ServiceList
-	ConfigMaps   *corev1.ConfigMapList
-	Secrets      *corev1.SecretList
-	PVCs         *corev1.PersistentVolumeClaimList
-	Pods         *corev1.PodList
-	Deployments  *appsv1.DeploymentList
-	StatefulSets *appsv1.StatefulSetList
-	DaemonSets   *appsv1.DaemonSetList
-	ReplicaSets  *appsv1.ReplicaSetList
-	Jobs         *batchv1.JobList
-	CronJobs     *batchv1.CronJobList
+	Services       *corev1.ServiceList
+	EndpointSlices *discoveryv1.EndpointSliceList
+	ConfigMaps     *corev1.ConfigMapList
+	Secrets        *corev1.SecretList
+	PVCs           *corev1.PersistentVolumeClaimList
+	Pods           *corev1.PodList
+	Deployments    *appsv1.DeploymentList
+	StatefulSets   *appsv1.StatefulSetList
+	DaemonSets     *appsv1.DaemonSetList
+	ReplicaSets    *appsv1.ReplicaSetList
+	Jobs           *batchv1.JobList
+	CronJobs       *batchv1.CronJobList
+
+	// Revision history of StatefulSets and DaemonSets
+	ControllerRevisions *appsv1.ControllerRevisionList
+
+	// Legacy workloads
+	ReplicationControllers *corev1.ReplicationControllerList
+
+	// Cluster-scoped resources, empty if the user cannot list them
+	Nodes             *corev1.NodeList
+	PersistentVolumes *corev1.PersistentVolumeList
+	StorageClasses    *storagev1.StorageClassList
+	VolumeAttachments *storagev1.VolumeAttachmentList
+
+	// Fetched only when looking for orphans, for the Secrets they name
+	Ingresses       *networkingv1.IngressList
+	ServiceAccounts *corev1.ServiceAccountList
+
+	// Custom resources named by relationship rules, fetched by kind
+	Custom map[schema.GroupKind][]*unstructured.Unstructured
+
+	idx *resourceIndex // Lookups built on first use
 }
 
 // NewClient creates a new Kubernetes client
 func NewClient(kubeconfig string) (*Client, error) {
 	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
 	if err != nil {
-		return nil, fmt.Errorf("error building kubeconfig: %v", err)
+		return nil, fmt.Errorf("error building kubeconfig: %w", err)
+	}
+	return newClientForConfig(config)
+}
+
+// NewClientForContext creates a Kubernetes client for a named kubeconfig context
+func NewClientForContext(kubeconfig, contextName string) (*Client, error) {
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeconfig},
+		&clientcmd.ConfigOverrides{CurrentContext: contextName},
+	).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("error building kubeconfig for context %q: %w", contextName, err)
 	}
+	return newClientForConfig(config)
+}
 
+// newClientForConfig creates the clients behind a Client from a REST config
+func newClientForConfig(config *rest.Config) (*Client, error) {
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
-		return nil, fmt.Errorf("error creating kubernetes client: %v", err)
+		return nil, fmt.Errorf("error creating kubernetes client: %w", err)
+	}
+
+	metrics, err := metricsclient.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("error creating metrics client: %w", err)
 	}
 
-	return &Client{clientset: clientset}, nil
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("error creating dynamic client: %w", err)
+	}
+
+	// Discovery results are cached, as each custom kind needs a lookup
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(clientset.Discovery()))
+
+	return &Client{clientset: clientset, metrics: metrics, dynamic: dynamicClient, mapper: mapper}, nil
 }
 
 // NamespaceExists checks if a namespace exists
@@ -64,74 +132,236 @@ func (c *Client) GetResources(namespace string) (*Resources, error) {
 	// Fetch Services
 	resources.Services, err = c.clientset.CoreV1().Services(namespace).List(ctx, opts)
 	if err != nil {
-		return nil, fmt.Errorf("error fetching services: %v", err)
+		return nil, fmt.Errorf("error fetching services: %w", err)
+	}
+
+	// Fetch EndpointSlices. Roles granting only core resources often leave
+	// them out, so a forbidden error leaves the list empty.
+	resources.EndpointSlices, err = c.clientset.DiscoveryV1().EndpointSlices(namespace).List(ctx, opts)
+	if apierrors.IsForbidden(err) {
+		resources.EndpointSlices, err = &discoveryv1.EndpointSliceList{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching endpointslices: %w", err)
 	}
 
 	// Fetch ConfigMaps
 	resources.ConfigMaps, err = c.clientset.CoreV1().ConfigMaps(namespace).List(ctx, opts)
 	if err != nil {
-		return nil, fmt.Errorf("error fetching configmaps: %v", err)
+		return nil, fmt.Errorf("error fetching configmaps: %w", err)
 	}
 
 	// Fetch Secrets
 	resources.Secrets, err = c.clientset.CoreV1().Secrets(namespace).List(ctx, opts)
 	if err != nil {
-		return nil, fmt.Errorf("error fetching secrets: %v", err)
+		return nil, fmt.Errorf("error fetching secrets: %w", err)
 	}
 
 	// Fetch PVCs
 	resources.PVCs, err = c.clientset.CoreV1().PersistentVolumeClaims(namespace).List(ctx, opts)
 	if err != nil {
-		return nil, fmt.Errorf("error fetching pvcs: %v", err)
+		return nil, fmt.Errorf("error fetching pvcs: %w", err)
 	}
 
 	// Fetch Pods
 	resources.Pods, err = c.clientset.CoreV1().Pods(namespace).List(ctx, opts)
 	if err != nil {
-		return nil, fmt.Errorf("error fetching pods: %v", err)
+		return nil, fmt.Errorf("error fetching pods: %w", err)
 	}
 
 	// Fetch Deployments
 	resources.Deployments, err = c.clientset.AppsV1().Deployments(namespace).List(ctx, opts)
 	if err != nil {
-		return nil, fmt.Errorf("error fetching deployments: %v", err)
+		return nil, fmt.Errorf("error fetching deployments: %w", err)
 	}
 
 	// Fetch StatefulSets
 	resources.StatefulSets, err = c.clientset.AppsV1().StatefulSets(namespace).List(ctx, opts)
 	if err != nil {
-		return nil, fmt.Errorf("error fetching statefulsets: %v", err)
+		return nil, fmt.Errorf("error fetching statefulsets: %w", err)
 	}
 
 	// Fetch DaemonSets
 	resources.DaemonSets, err = c.clientset.AppsV1().DaemonSets(namespace).List(ctx, opts)
 	if err != nil {
-		return nil, fmt.Errorf("error fetching daemonsets: %v", err)
+		return nil, fmt.Errorf("error fetching daemonsets: %w", err)
 	}
 
 	// Fetch ReplicaSets
 	resources.ReplicaSets, err = c.clientset.AppsV1().ReplicaSets(namespace).List(ctx, opts)
 	if err != nil {
-		return nil, fmt.Errorf("error fetching replicasets: %v", err)
+		return nil, fmt.Errorf("error fetching replicasets: %w", err)
+	}
+
+	// Fetch ReplicationControllers. Roles written for current workloads often
+	// leave these out, so a forbidden error leaves the list empty.
+	resources.ReplicationControllers, err = c.clientset.CoreV1().ReplicationControllers(namespace).List(ctx, opts)
+	if apierrors.IsForbidden(err) {
+		resources.ReplicationControllers, err = &corev1.ReplicationControllerList{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching replicationcontrollers: %w", err)
+	}
+
+	// Fetch ControllerRevisions. Revision history is optional, so a forbidden
+	// error leaves it empty.
+	resources.ControllerRevisions, err = c.clientset.AppsV1().ControllerRevisions(namespace).List(ctx, opts)
+	if apierrors.IsForbidden(err) {
+		resources.ControllerRevisions, err = &appsv1.ControllerRevisionList{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching controllerrevisions: %w", err)
 	}
 
 	// Fetch Jobs
 	resources.Jobs, err = c.clientset.BatchV1().Jobs(namespace).List(ctx, opts)
 	if err != nil {
-		return nil, fmt.Errorf("error fetching jobs: %v", err)
+		return nil, fmt.Errorf("error fetching jobs: %w", err)
 	}
 
 	// Fetch CronJobs
 	resources.CronJobs, err = c.clientset.BatchV1().CronJobs(namespace).List(ctx, opts)
 	if err != nil {
-		return nil, fmt.Errorf("error fetching cronjobs: %v", err)
+		return nil, fmt.Errorf("error fetching cronjobs: %w", err)
+	}
+
+	// Nodes are only needed by some views, which fetch them with GetNodes
+	resources.Nodes = &corev1.NodeList{}
+
+	// Fetch the cluster-scoped storage behind the namespace's PVCs
+	if err := c.getStorage(ctx, resources); err != nil {
+		return nil, err
 	}
 
 	return resources, nil
 }
 
+// getStorage fetches the PersistentVolumes bound to the namespace's PVCs by
+// name, the StorageClasses they use and, if any volume is bound, the
+// VolumeAttachments. Namespace-scoped users are often not allowed to read
+// these, so a forbidden or missing object is left out.
+func (c *Client) getStorage(ctx context.Context, resources *Resources) error {
+	resources.PersistentVolumes = &corev1.PersistentVolumeList{}
+	resources.StorageClasses = &storagev1.StorageClassList{}
+	resources.VolumeAttachments = &storagev1.VolumeAttachmentList{}
+
+	var classNames []string
+	seen := make(map[string]bool)
+	addClass := func(name string) {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			classNames = append(classNames, name)
+		}
+	}
+
+	for _, pvc := range resources.PVCs.Items {
+		if pvc.Spec.StorageClassName != nil {
+			addClass(*pvc.Spec.StorageClassName)
+		}
+		if pvc.Spec.VolumeName == "" {
+			continue
+		}
+		pv, err := c.clientset.CoreV1().PersistentVolumes().Get(ctx, pvc.Spec.VolumeName, metav1.GetOptions{})
+		if apierrors.IsForbidden(err) || apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("error fetching persistentvolume %s: %w", pvc.Spec.VolumeName, err)
+		}
+		resources.PersistentVolumes.Items = append(resources.PersistentVolumes.Items, *pv)
+		addClass(pv.Spec.StorageClassName)
+	}
+
+	for _, name := range classNames {
+		sc, err := c.clientset.StorageV1().StorageClasses().Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsForbidden(err) || apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("error fetching storageclass %s: %w", name, err)
+		}
+		resources.StorageClasses.Items = append(resources.StorageClasses.Items, *sc)
+	}
+
+	// VolumeAttachments cannot be selected by volume, so list them only when needed
+	if len(resources.PersistentVolumes.Items) == 0 {
+		return nil
+	}
+	attachments, err := c.clientset.StorageV1().VolumeAttachments().List(ctx, metav1.ListOptions{})
+	if apierrors.IsForbidden(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error fetching volumeattachments: %w", err)
+	}
+	resources.VolumeAttachments = attachments
+	return nil
+}
+
+// GetNodes lists the cluster's Nodes. Namespace-scoped users are often not
+// allowed to, so a forbidden error returns an empty list.
+func (c *Client) GetNodes() (*corev1.NodeList, error) {
+	nodes, err := c.clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if apierrors.IsForbidden(err) {
+		return &corev1.NodeList{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching nodes: %w", err)
+	}
+	return nodes, nil
+}
+
 // Add this method if it doesn't exist
 // GetPod retrieves a pod by name in the specified namespace
 func (c *Client) GetPod(namespace, name string) (*corev1.Pod, error) {
     return c.clientset.CoreV1().Pods(namespace).Get(context.TODO(), name, metav1.GetOptions{})
 }
+
+// GetPodLogs returns the last tailLines lines of logs for a container in a pod
+func (c *Client) GetPodLogs(namespace, pod, container string, tailLines int64) (string, error) {
+	opts := &corev1.PodLogOptions{
+		Container: container,
+		TailLines: &tailLines,
+	}
+	data, err := c.clientset.CoreV1().Pods(namespace).GetLogs(pod, opts).DoRaw(context.TODO())
+	if err != nil {
+		return "", fmt.Errorf("error fetching logs: %w", err)
+	}
+	return string(data), nil
+}
+
+// GetPodMetrics fetches current pod usage from the metrics API
+func (c *Client) GetPodMetrics(namespace string) (*metricsv1beta1.PodMetricsList, error) {
+	metrics, err := c.metrics.MetricsV1beta1().PodMetricses(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching pod metrics: %w", err)
+	}
+	return metrics, nil
+}
+
+// GetEvents fetches the events in the specified namespace
+func (c *Client) GetEvents(namespace string) (*corev1.EventList, error) {
+	events, err := c.clientset.CoreV1().Events(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching events: %w", err)
+	}
+	return events, nil
+}
+
+// GetServiceAccounts fetches the ServiceAccounts in the specified namespace
+func (c *Client) GetServiceAccounts(namespace string) (*corev1.ServiceAccountList, error) {
+	serviceAccounts, err := c.clientset.CoreV1().ServiceAccounts(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching serviceaccounts: %w", err)
+	}
+	return serviceAccounts, nil
+}
+
+// GetPodDisruptionBudgets fetches the PodDisruptionBudgets in the specified namespace
+func (c *Client) GetPodDisruptionBudgets(namespace string) (*policyv1.PodDisruptionBudgetList, error) {
+	pdbs, err := c.clientset.PolicyV1().PodDisruptionBudgets(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching poddisruptionbudgets: %w", err)
+	}
+	return pdbs, nil
+}
@@ -0,0 +1,180 @@
+package k8s
+
+import (
+	"context"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ReferenceGrant is the Gateway API kind that admits references from other namespaces
+var ReferenceGrant = schema.GroupKind{Group: "gateway.networking.k8s.io", Kind: "ReferenceGrant"}
+
+// ObjectRef names an object by kind, namespace and name
+type ObjectRef struct {
+	Kind      schema.GroupKind
+	Namespace string
+	Name      string
+}
+
+// GetService fetches a Service from any namespace
+func (c *Client) GetService(namespace, name string) (*corev1.Service, error) {
+	return c.clientset.CoreV1().Services(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+}
+
+// GetServiceAccount fetches a ServiceAccount from any namespace
+func (c *Client) GetServiceAccount(namespace, name string) (*corev1.ServiceAccount, error) {
+	return c.clientset.CoreV1().ServiceAccounts(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+}
+
+// GetIngresses lists the Ingresses in a namespace
+func (c *Client) GetIngresses(namespace string) (*networkingv1.IngressList, error) {
+	return c.clientset.NetworkingV1().Ingresses(namespace).List(context.TODO(), metav1.ListOptions{})
+}
+
+// GetRoleBindings lists the RoleBindings in a namespace
+func (c *Client) GetRoleBindings(namespace string) (*rbacv1.RoleBindingList, error) {
+	return c.clientset.RbacV1().RoleBindings(namespace).List(context.TODO(), metav1.ListOptions{})
+}
+
+// ExternalNameTarget returns the in-cluster Service an ExternalName Service
+// points at, from a name of the form <service>.<namespace>.svc[.<cluster domain>]
+func ExternalNameTarget(svc *corev1.Service) (namespace, name string, ok bool) {
+	if svc.Spec.Type != corev1.ServiceTypeExternalName {
+		return "", "", false
+	}
+	parts := strings.Split(strings.TrimSuffix(svc.Spec.ExternalName, "."), ".")
+	if len(parts) < 3 || parts[2] != "svc" || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[1], parts[0], true
+}
+
+// IngressServiceNames returns the names of the Services an Ingress routes to
+func IngressServiceNames(ingress *networkingv1.Ingress) []string {
+	var names []string
+	seen := make(map[string]bool)
+	add := func(backend *networkingv1.IngressBackend) {
+		if backend != nil && backend.Service != nil && !seen[backend.Service.Name] {
+			seen[backend.Service.Name] = true
+			names = append(names, backend.Service.Name)
+		}
+	}
+
+	add(ingress.Spec.DefaultBackend)
+	for _, rule := range ingress.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		for i := range rule.HTTP.Paths {
+			add(&rule.HTTP.Paths[i].Backend)
+		}
+	}
+	return names
+}
+
+// ForeignServiceAccounts returns the ServiceAccount subjects of a RoleBinding
+// that live in another namespace
+func ForeignServiceAccounts(binding *rbacv1.RoleBinding) []ObjectRef {
+	var refs []ObjectRef
+	for _, subject := range binding.Subjects {
+		if subject.Kind == rbacv1.ServiceAccountKind && subject.Namespace != "" && subject.Namespace != binding.Namespace {
+			refs = append(refs, ObjectRef{Kind: schema.GroupKind{Kind: "ServiceAccount"}, Namespace: subject.Namespace, Name: subject.Name})
+		}
+	}
+	return refs
+}
+
+// ReferenceGrantFrom is one entry of a ReferenceGrant's spec.from
+type ReferenceGrantFrom struct {
+	Kind      schema.GroupKind
+	Namespace string
+}
+
+// ReferenceGrantSpec returns the kinds and namespaces a ReferenceGrant admits
+// references from, and the local objects it admits references to. A target
+// with an empty name admits every object of its kind.
+func ReferenceGrantSpec(grant *unstructured.Unstructured) ([]ReferenceGrantFrom, []ObjectRef) {
+	var from []ReferenceGrantFrom
+	entries, _, _ := unstructured.NestedSlice(grant.Object, "spec", "from")
+	for _, e := range entries {
+		entry, ok := e.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		group, _ := entry["group"].(string)
+		kind, _ := entry["kind"].(string)
+		namespace, _ := entry["namespace"].(string)
+		from = append(from, ReferenceGrantFrom{Kind: schema.GroupKind{Group: group, Kind: kind}, Namespace: namespace})
+	}
+
+	var to []ObjectRef
+	entries, _, _ = unstructured.NestedSlice(grant.Object, "spec", "to")
+	for _, e := range entries {
+		entry, ok := e.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		group, _ := entry["group"].(string)
+		kind, _ := entry["kind"].(string)
+		name, _ := entry["name"].(string)
+		to = append(to, ObjectRef{Kind: schema.GroupKind{Group: group, Kind: kind}, Namespace: grant.GetNamespace(), Name: name})
+	}
+	return from, to
+}
+
+// GatewayRefsInto returns the references a Gateway API route or Gateway makes
+// to objects in the given namespace: route backendRefs, which default to
+// Services, and Gateway listener certificateRefs, which default to Secrets
+func GatewayRefsInto(obj *unstructured.Unstructured, namespace string) []ObjectRef {
+	var refs []ObjectRef
+	add := func(ref map[string]interface{}, defaultKind string) {
+		refNamespace, _ := ref["namespace"].(string)
+		if refNamespace != namespace {
+			return
+		}
+		group, _ := ref["group"].(string)
+		kind, _ := ref["kind"].(string)
+		if kind == "" {
+			kind = defaultKind
+		}
+		name, _ := ref["name"].(string)
+		refs = append(refs, ObjectRef{Kind: schema.GroupKind{Group: group, Kind: kind}, Namespace: refNamespace, Name: name})
+	}
+
+	if obj.GetKind() == "Gateway" {
+		listeners, _, _ := unstructured.NestedSlice(obj.Object, "spec", "listeners")
+		for _, l := range listeners {
+			listener, _ := l.(map[string]interface{})
+			certificateRefs, _, _ := unstructured.NestedSlice(listener, "tls", "certificateRefs")
+			for _, r := range certificateRefs {
+				if ref, ok := r.(map[string]interface{}); ok {
+					add(ref, "Secret")
+				}
+			}
+		}
+		return refs
+	}
+
+	rules, _, _ := unstructured.NestedSlice(obj.Object, "spec", "rules")
+	for _, r := range rules {
+		rule, _ := r.(map[string]interface{})
+		backendRefs, _, _ := unstructured.NestedSlice(rule, "backendRefs")
+		for _, b := range backendRefs {
+			if ref, ok := b.(map[string]interface{}); ok {
+				add(ref, "Service")
+			}
+		}
+	}
+	return refs
+}
+
+// Admits reports whether a ReferenceGrant target covers a reference
+func (to ObjectRef) Admits(ref ObjectRef) bool {
+	return to.Kind == ref.Kind && (to.Name == "" || to.Name == ref.Name)
+}
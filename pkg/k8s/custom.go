@@ -0,0 +1,200 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// builtinKinds are the kinds GetResources fetches into typed lists
+var builtinKinds = map[schema.GroupKind]bool{
+	{Kind: "Service"}: true,
+	{Group: "discovery.k8s.io", Kind: "EndpointSlice"}: true,
+	{Kind: "ConfigMap"}:                                 true,
+	{Kind: "Secret"}:                                    true,
+	{Kind: "PersistentVolumeClaim"}:                     true,
+	{Kind: "Pod"}:                                       true,
+	{Group: "apps", Kind: "Deployment"}:                 true,
+	{Group: "apps", Kind: "StatefulSet"}:                true,
+	{Group: "apps", Kind: "DaemonSet"}:                  true,
+	{Group: "apps", Kind: "ReplicaSet"}:                 true,
+	{Group: "batch", Kind: "Job"}:                       true,
+	{Group: "batch", Kind: "CronJob"}:                   true,
+	{Group: "apps", Kind: "ControllerRevision"}:         true,
+	{Kind: "ReplicationController"}:                     true,
+	{Kind: "Node"}:                                      true,
+	{Kind: "PersistentVolume"}:                          true,
+	{Group: "storage.k8s.io", Kind: "StorageClass"}:     true,
+	{Group: "storage.k8s.io", Kind: "VolumeAttachment"}: true,
+}
+
+// IsBuiltinKind reports whether GetResources fetches a kind
+func IsBuiltinKind(gk schema.GroupKind) bool {
+	return builtinKinds[gk]
+}
+
+// GetCustomResources lists the objects of a kind through the dynamic client,
+// in the namespace for namespaced kinds and cluster-wide otherwise
+func (c *Client) GetCustomResources(namespace string, gk schema.GroupKind) ([]*unstructured.Unstructured, error) {
+	mapping, err := c.mapper.RESTMapping(gk)
+	if err != nil {
+		return nil, fmt.Errorf("error finding %s: %w", gk, err)
+	}
+
+	resource := c.dynamic.Resource(mapping.Resource)
+	var list *unstructured.UnstructuredList
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		list, err = resource.Namespace(namespace).List(context.TODO(), metav1.ListOptions{})
+	} else {
+		list, err = resource.List(context.TODO(), metav1.ListOptions{})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching %s: %w", gk, err)
+	}
+
+	objects := make([]*unstructured.Unstructured, len(list.Items))
+	for i := range list.Items {
+		objects[i] = &list.Items[i]
+	}
+	return objects, nil
+}
+
+// ObjectsOfKind returns the fetched objects of a kind, built-in or custom
+func (r *Resources) ObjectsOfKind(gk schema.GroupKind) []metav1.Object {
+	var objects []metav1.Object
+	switch gk {
+	case schema.GroupKind{Kind: "Service"}:
+		for i := range r.Services.Items {
+			objects = append(objects, &r.Services.Items[i])
+		}
+	case schema.GroupKind{Group: "discovery.k8s.io", Kind: "EndpointSlice"}:
+		for i := range r.EndpointSlices.Items {
+			objects = append(objects, &r.EndpointSlices.Items[i])
+		}
+	case schema.GroupKind{Kind: "ConfigMap"}:
+		for i := range r.ConfigMaps.Items {
+			objects = append(objects, &r.ConfigMaps.Items[i])
+		}
+	case schema.GroupKind{Kind: "Secret"}:
+		for i := range r.Secrets.Items {
+			objects = append(objects, &r.Secrets.Items[i])
+		}
+	case schema.GroupKind{Kind: "PersistentVolumeClaim"}:
+		for i := range r.PVCs.Items {
+			objects = append(objects, &r.PVCs.Items[i])
+		}
+	case schema.GroupKind{Kind: "Pod"}:
+		for i := range r.Pods.Items {
+			objects = append(objects, &r.Pods.Items[i])
+		}
+	case schema.GroupKind{Group: "apps", Kind: "Deployment"}:
+		for i := range r.Deployments.Items {
+			objects = append(objects, &r.Deployments.Items[i])
+		}
+	case schema.GroupKind{Group: "apps", Kind: "StatefulSet"}:
+		for i := range r.StatefulSets.Items {
+			objects = append(objects, &r.StatefulSets.Items[i])
+		}
+	case schema.GroupKind{Group: "apps", Kind: "DaemonSet"}:
+		for i := range r.DaemonSets.Items {
+			objects = append(objects, &r.DaemonSets.Items[i])
+		}
+	case schema.GroupKind{Group: "apps", Kind: "ReplicaSet"}:
+		for i := range r.ReplicaSets.Items {
+			objects = append(objects, &r.ReplicaSets.Items[i])
+		}
+	case schema.GroupKind{Group: "batch", Kind: "Job"}:
+		for i := range r.Jobs.Items {
+			objects = append(objects, &r.Jobs.Items[i])
+		}
+	case schema.GroupKind{Group: "batch", Kind: "CronJob"}:
+		for i := range r.CronJobs.Items {
+			objects = append(objects, &r.CronJobs.Items[i])
+		}
+	case schema.GroupKind{Group: "apps", Kind: "ControllerRevision"}:
+		for i := range r.ControllerRevisions.Items {
+			objects = append(objects, &r.ControllerRevisions.Items[i])
+		}
+	case schema.GroupKind{Kind: "ReplicationController"}:
+		for i := range r.ReplicationControllers.Items {
+			objects = append(objects, &r.ReplicationControllers.Items[i])
+		}
+	case schema.GroupKind{Kind: "Node"}:
+		for i := range r.Nodes.Items {
+			objects = append(objects, &r.Nodes.Items[i])
+		}
+	case schema.GroupKind{Kind: "PersistentVolume"}:
+		for i := range r.PersistentVolumes.Items {
+			objects = append(objects, &r.PersistentVolumes.Items[i])
+		}
+	case schema.GroupKind{Group: "storage.k8s.io", Kind: "StorageClass"}:
+		for i := range r.StorageClasses.Items {
+			objects = append(objects, &r.StorageClasses.Items[i])
+		}
+	case schema.GroupKind{Group: "storage.k8s.io", Kind: "VolumeAttachment"}:
+		for i := range r.VolumeAttachments.Items {
+			objects = append(objects, &r.VolumeAttachments.Items[i])
+		}
+	default:
+		for _, obj := range r.Custom[gk] {
+			objects = append(objects, obj)
+		}
+	}
+	return objects
+}
+
+// GetObject returns the fetched object of a kind with the given name, or nil.
+// Each kind's name lookup is built on first use.
+func (r *Resources) GetObject(gk schema.GroupKind, name string) metav1.Object {
+	idx := r.index()
+	names, ok := idx.objectsByKind[gk]
+	if !ok {
+		names = make(map[string]metav1.Object)
+		for _, obj := range r.ObjectsOfKind(gk) {
+			names[obj.GetName()] = obj
+		}
+		idx.objectsByKind[gk] = names
+	}
+	return names[name]
+}
+
+// ObjectsMatchingSelector returns the fetched objects of a kind whose labels
+// include every pair in the selector
+func (r *Resources) ObjectsMatchingSelector(gk schema.GroupKind, selector map[string]string) []metav1.Object {
+	if len(selector) == 0 {
+		return nil
+	}
+
+	var objects []metav1.Object
+	if gk == (schema.GroupKind{Kind: "Pod"}) {
+		for _, pod := range r.PodsMatchingSelector(selector) {
+			objects = append(objects, pod)
+		}
+		return objects
+	}
+
+	for _, obj := range r.ObjectsOfKind(gk) {
+		if labelsInclude(obj.GetLabels(), selector) {
+			objects = append(objects, obj)
+		}
+	}
+	return objects
+}
+
+// GetCustomResource fetches a single object of a kind through the dynamic client
+func (c *Client) GetCustomResource(gk schema.GroupKind, namespace, name string) (*unstructured.Unstructured, error) {
+	mapping, err := c.mapper.RESTMapping(gk)
+	if err != nil {
+		return nil, fmt.Errorf("error finding %s: %w", gk, err)
+	}
+
+	resource := c.dynamic.Resource(mapping.Resource)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		return resource.Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	}
+	return resource.Get(context.TODO(), name, metav1.GetOptions{})
+}
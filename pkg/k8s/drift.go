@@ -0,0 +1,327 @@
+package k8s
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// FieldDiff is a field whose desired value differs from the live one. An empty
+// value means the field, container or key is absent on that side.
+type FieldDiff struct {
+	Path    string
+	Desired string
+	Live    string
+}
+
+// DiffObjects compares the fields of a desired object that a manual change
+// would touch against the live object of the same kind: replicas, pod template
+// containers and volumes, CronJob schedules, ConfigMap and Secret keys, and
+// Service type, selector and ports. Fields the API server defaults are only
+// compared when the desired object sets them, and Secret values are never
+// included in the result.
+func DiffObjects(desired, live metav1.Object) []FieldDiff {
+	var diffs []FieldDiff
+	switch d := desired.(type) {
+	case *appsv1.Deployment:
+		if l, ok := live.(*appsv1.Deployment); ok {
+			diffs = diffReplicas(d.Spec.Replicas, l.Spec.Replicas)
+		}
+	case *appsv1.StatefulSet:
+		if l, ok := live.(*appsv1.StatefulSet); ok {
+			diffs = diffReplicas(d.Spec.Replicas, l.Spec.Replicas)
+		}
+	case *corev1.ReplicationController:
+		if l, ok := live.(*corev1.ReplicationController); ok {
+			diffs = diffReplicas(d.Spec.Replicas, l.Spec.Replicas)
+		}
+	case *batchv1.CronJob:
+		if l, ok := live.(*batchv1.CronJob); ok {
+			diffs = diffValue(diffs, "schedule", d.Spec.Schedule, l.Spec.Schedule)
+			if d.Spec.Suspend != nil {
+				diffs = diffValue(diffs, "suspend", fmt.Sprint(*d.Spec.Suspend), fmt.Sprint(l.Spec.Suspend != nil && *l.Spec.Suspend))
+			}
+		}
+	case *corev1.ConfigMap:
+		if l, ok := live.(*corev1.ConfigMap); ok {
+			diffs = diffConfigMap(d, l)
+		}
+	case *corev1.Secret:
+		if l, ok := live.(*corev1.Secret); ok {
+			diffs = diffSecret(d, l)
+		}
+	case *corev1.Service:
+		if l, ok := live.(*corev1.Service); ok {
+			diffs = diffService(d, l)
+		}
+	}
+
+	if template := PodTemplateOf(desired); template != nil {
+		if liveTemplate := PodTemplateOf(live); liveTemplate != nil {
+			diffs = append(diffs, diffPodSpec(&template.Spec, &liveTemplate.Spec)...)
+		}
+	}
+	return diffs
+}
+
+// diffValue appends a diff if two values differ
+func diffValue(diffs []FieldDiff, path, desired, live string) []FieldDiff {
+	if desired != live {
+		diffs = append(diffs, FieldDiff{Path: path, Desired: desired, Live: live})
+	}
+	return diffs
+}
+
+// diffReplicas compares replica counts when the desired object sets one
+func diffReplicas(desired, live *int32) []FieldDiff {
+	if desired == nil {
+		return nil
+	}
+	liveReplicas := int32(1)
+	if live != nil {
+		liveReplicas = *live
+	}
+	return diffValue(nil, "replicas", fmt.Sprint(*desired), fmt.Sprint(liveReplicas))
+}
+
+// diffPodSpec compares the containers, service account and volumes of two pod specs
+func diffPodSpec(desired, live *corev1.PodSpec) []FieldDiff {
+	diffs := diffContainers("initContainers", desired.InitContainers, live.InitContainers)
+	diffs = append(diffs, diffContainers("containers", desired.Containers, live.Containers)...)
+	if desired.ServiceAccountName != "" {
+		diffs = diffValue(diffs, "serviceAccountName", desired.ServiceAccountName, live.ServiceAccountName)
+	}
+
+	desiredVolumes := make(map[string]string)
+	for _, volume := range desired.Volumes {
+		desiredVolumes[volume.Name] = volumeSource(&volume)
+	}
+	liveVolumes := make(map[string]string)
+	for _, volume := range live.Volumes {
+		liveVolumes[volume.Name] = volumeSource(&volume)
+	}
+	return diffMaps(diffs, "volumes", desiredVolumes, liveVolumes)
+}
+
+// volumeSource describes what a volume mounts, by the object it names where it names one
+func volumeSource(volume *corev1.Volume) string {
+	switch {
+	case volume.ConfigMap != nil:
+		return "configMap/" + volume.ConfigMap.Name
+	case volume.Secret != nil:
+		return "secret/" + volume.Secret.SecretName
+	case volume.PersistentVolumeClaim != nil:
+		return "persistentVolumeClaim/" + volume.PersistentVolumeClaim.ClaimName
+	case volume.EmptyDir != nil:
+		return "emptyDir"
+	case volume.HostPath != nil:
+		return "hostPath/" + volume.HostPath.Path
+	case volume.Projected != nil:
+		return "projected"
+	default:
+		return "other"
+	}
+}
+
+// diffContainers compares containers by name: image, command, args,
+// environment and resources
+func diffContainers(path string, desired, live []corev1.Container) []FieldDiff {
+	var diffs []FieldDiff
+	liveByName := make(map[string]*corev1.Container)
+	for i := range live {
+		liveByName[live[i].Name] = &live[i]
+	}
+	desiredNames := make(map[string]bool)
+
+	for i := range desired {
+		d := &desired[i]
+		desiredNames[d.Name] = true
+		prefix := path + "[" + d.Name + "]"
+		l, ok := liveByName[d.Name]
+		if !ok {
+			diffs = append(diffs, FieldDiff{Path: prefix, Desired: d.Image})
+			continue
+		}
+
+		diffs = diffValue(diffs, prefix+".image", d.Image, l.Image)
+		diffs = diffValue(diffs, prefix+".command", strings.Join(d.Command, " "), strings.Join(l.Command, " "))
+		diffs = diffValue(diffs, prefix+".args", strings.Join(d.Args, " "), strings.Join(l.Args, " "))
+		diffs = diffMaps(diffs, prefix+".env", envValues(d.Env), envValues(l.Env))
+		diffs = diffMaps(diffs, prefix+".envFrom", envFromSources(d.EnvFrom), envFromSources(l.EnvFrom))
+		diffs = diffMaps(diffs, prefix+".resources.requests", quantities(d.Resources.Requests), quantities(l.Resources.Requests))
+		diffs = diffMaps(diffs, prefix+".resources.limits", quantities(d.Resources.Limits), quantities(l.Resources.Limits))
+	}
+
+	for i := range live {
+		if !desiredNames[live[i].Name] {
+			diffs = append(diffs, FieldDiff{Path: path + "[" + live[i].Name + "]", Live: live[i].Image})
+		}
+	}
+	return diffs
+}
+
+// envValues maps environment variable names to their value or source
+func envValues(env []corev1.EnvVar) map[string]string {
+	values := make(map[string]string)
+	for _, e := range env {
+		switch {
+		case e.ValueFrom == nil:
+			values[e.Name] = e.Value
+		case e.ValueFrom.ConfigMapKeyRef != nil:
+			values[e.Name] = "configMapKeyRef/" + e.ValueFrom.ConfigMapKeyRef.Name + "/" + e.ValueFrom.ConfigMapKeyRef.Key
+		case e.ValueFrom.SecretKeyRef != nil:
+			values[e.Name] = "secretKeyRef/" + e.ValueFrom.SecretKeyRef.Name + "/" + e.ValueFrom.SecretKeyRef.Key
+		case e.ValueFrom.FieldRef != nil:
+			values[e.Name] = "fieldRef/" + e.ValueFrom.FieldRef.FieldPath
+		case e.ValueFrom.ResourceFieldRef != nil:
+			values[e.Name] = "resourceFieldRef/" + e.ValueFrom.ResourceFieldRef.Resource
+		default:
+			values[e.Name] = "valueFrom"
+		}
+	}
+	return values
+}
+
+// envFromSources keys envFrom entries by the object they import
+func envFromSources(sources []corev1.EnvFromSource) map[string]string {
+	values := make(map[string]string)
+	for _, source := range sources {
+		switch {
+		case source.ConfigMapRef != nil:
+			values["configMap/"+source.ConfigMapRef.Name] = "prefix=" + source.Prefix
+		case source.SecretRef != nil:
+			values["secret/"+source.SecretRef.Name] = "prefix=" + source.Prefix
+		}
+	}
+	return values
+}
+
+// quantities formats resource quantities canonically, so 0.5 and 500m compare equal
+func quantities(list corev1.ResourceList) map[string]string {
+	values := make(map[string]string)
+	for name, quantity := range list {
+		values[string(name)] = quantity.String()
+	}
+	return values
+}
+
+// diffConfigMap compares ConfigMap keys and values
+func diffConfigMap(desired, live *corev1.ConfigMap) []FieldDiff {
+	diffs := diffMaps(nil, "data", desired.Data, live.Data)
+	for _, key := range changedKeys(desired.BinaryData, live.BinaryData) {
+		diffs = append(diffs, hiddenValueDiff("binaryData", key, desired.BinaryData, live.BinaryData))
+	}
+	return diffs
+}
+
+// diffSecret compares Secret types and keys, reporting only whether each key
+// is present and whether its value changed
+func diffSecret(desired, live *corev1.Secret) []FieldDiff {
+	var diffs []FieldDiff
+	if desired.Type != "" {
+		diffs = diffValue(diffs, "type", string(desired.Type), string(live.Type))
+	}
+	for _, key := range changedKeys(desired.Data, live.Data) {
+		diffs = append(diffs, hiddenValueDiff("data", key, desired.Data, live.Data))
+	}
+	return diffs
+}
+
+// diffService compares a Service's type, selector and ports
+func diffService(desired, live *corev1.Service) []FieldDiff {
+	var diffs []FieldDiff
+	serviceType := desired.Spec.Type
+	if serviceType == "" {
+		serviceType = corev1.ServiceTypeClusterIP
+	}
+	diffs = diffValue(diffs, "type", string(serviceType), string(live.Spec.Type))
+	diffs = diffMaps(diffs, "selector", desired.Spec.Selector, live.Spec.Selector)
+
+	livePorts := make(map[string]string)
+	for _, port := range live.Spec.Ports {
+		livePorts[servicePortKey(&port)] = fmt.Sprintf("%d->%s", port.Port, port.TargetPort.String())
+	}
+	desiredPorts := make(map[string]string)
+	for _, port := range desired.Spec.Ports {
+		// An unset targetPort defaults to the port
+		target := port.TargetPort.String()
+		if port.TargetPort.IntValue() == 0 && port.TargetPort.StrVal == "" {
+			target = fmt.Sprint(port.Port)
+		}
+		desiredPorts[servicePortKey(&port)] = fmt.Sprintf("%d->%s", port.Port, target)
+	}
+	return diffMaps(diffs, "ports", desiredPorts, livePorts)
+}
+
+// servicePortKey identifies a Service port by name, or by number and protocol if unnamed
+func servicePortKey(port *corev1.ServicePort) string {
+	if port.Name != "" {
+		return port.Name
+	}
+	protocol := port.Protocol
+	if protocol == "" {
+		protocol = corev1.ProtocolTCP
+	}
+	return fmt.Sprintf("%d/%s", port.Port, protocol)
+}
+
+// diffMaps appends a diff for each key whose value differs between two maps,
+// in key order
+func diffMaps(diffs []FieldDiff, path string, desired, live map[string]string) []FieldDiff {
+	keys := make(map[string]bool)
+	for key := range desired {
+		keys[key] = true
+	}
+	for key := range live {
+		keys[key] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	for _, key := range sorted {
+		diffs = diffValue(diffs, path+"["+key+"]", desired[key], live[key])
+	}
+	return diffs
+}
+
+// changedKeys returns the keys, in order, that are on one side only or whose
+// values differ
+func changedKeys(desired, live map[string][]byte) []string {
+	var keys []string
+	for key, value := range desired {
+		if liveValue, ok := live[key]; !ok || !bytes.Equal(value, liveValue) {
+			keys = append(keys, key)
+		}
+	}
+	for key := range live {
+		if _, ok := desired[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// hiddenValueDiff describes a changed binary or secret key without its values
+func hiddenValueDiff(path, key string, desired, live map[string][]byte) FieldDiff {
+	diff := FieldDiff{Path: path + "[" + key + "]"}
+	if _, ok := desired[key]; ok {
+		diff.Desired = "set"
+	}
+	if _, ok := live[key]; ok {
+		diff.Live = "set"
+		if diff.Desired != "" {
+			diff.Live = "changed"
+		}
+	}
+	return diff
+}
@@ -0,0 +1,215 @@
+package k8s
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestDiffObjects(t *testing.T) {
+	replicas := func(n int32) *int32 { return &n }
+	deployment := func(mutate func(*appsv1.Deployment)) *appsv1.Deployment {
+		dep := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web"},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: replicas(2),
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{
+								Name:  "app",
+								Image: "web:1.0",
+								Env: []corev1.EnvVar{
+									{Name: "MODE", Value: "prod"},
+									{Name: "PORT", Value: "8080"},
+								},
+								Resources: corev1.ResourceRequirements{
+									Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("0.5")},
+								},
+							},
+							{Name: "proxy", Image: "envoy:1.30"},
+						},
+						Volumes: []corev1.Volume{
+							{Name: "config", VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "web"}}}},
+							{Name: "cache", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+						},
+					},
+				},
+			},
+		}
+		if mutate != nil {
+			mutate(dep)
+		}
+		return dep
+	}
+	service := func(mutate func(*corev1.Service)) *corev1.Service {
+		svc := &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "web"},
+			Spec: corev1.ServiceSpec{
+				Selector: map[string]string{"app": "web"},
+				Ports: []corev1.ServicePort{
+					{Name: "http", Port: 80, TargetPort: intstr.FromInt32(8080)},
+					{Name: "metrics", Port: 9090},
+				},
+			},
+		}
+		if mutate != nil {
+			mutate(svc)
+		}
+		return svc
+	}
+
+	tests := []struct {
+		name    string
+		desired metav1.Object
+		live    metav1.Object
+		want    []FieldDiff
+	}{
+		{
+			name:    "identical",
+			desired: deployment(nil),
+			live:    deployment(nil),
+		},
+		{
+			name:    "ignored metadata",
+			desired: deployment(nil),
+			live: deployment(func(d *appsv1.Deployment) {
+				d.Labels = map[string]string{"pod-template-hash": "abc"}
+				d.Annotations = map[string]string{"deployment.kubernetes.io/revision": "3"}
+				d.ResourceVersion = "12345"
+				d.UID = "uid"
+				d.Generation = 3
+			}),
+		},
+		{
+			name:    "defaulted replicas",
+			desired: deployment(func(d *appsv1.Deployment) { d.Spec.Replicas = nil }),
+			live:    deployment(func(d *appsv1.Deployment) { d.Spec.Replicas = replicas(5) }),
+		},
+		{
+			name:    "defaulted service account",
+			desired: deployment(nil),
+			live:    deployment(func(d *appsv1.Deployment) { d.Spec.Template.Spec.ServiceAccountName = "default" }),
+		},
+		{
+			name:    "equal quantities",
+			desired: deployment(nil),
+			live: deployment(func(d *appsv1.Deployment) {
+				d.Spec.Template.Spec.Containers[0].Resources.Requests[corev1.ResourceCPU] = resource.MustParse("500m")
+			}),
+		},
+		{
+			name:    "list ordering",
+			desired: deployment(nil),
+			live: deployment(func(d *appsv1.Deployment) {
+				spec := &d.Spec.Template.Spec
+				spec.Containers[0], spec.Containers[1] = spec.Containers[1], spec.Containers[0]
+				env := spec.Containers[1].Env
+				env[0], env[1] = env[1], env[0]
+				spec.Volumes[0], spec.Volumes[1] = spec.Volumes[1], spec.Volumes[0]
+			}),
+		},
+		{
+			name:    "scaled",
+			desired: deployment(nil),
+			live:    deployment(func(d *appsv1.Deployment) { d.Spec.Replicas = replicas(5) }),
+			want:    []FieldDiff{{Path: "replicas", Desired: "2", Live: "5"}},
+		},
+		{
+			name:    "image and env",
+			desired: deployment(nil),
+			live: deployment(func(d *appsv1.Deployment) {
+				d.Spec.Template.Spec.Containers[0].Image = "web:1.1"
+				d.Spec.Template.Spec.Containers[0].Env[0].Value = "debug"
+			}),
+			want: []FieldDiff{
+				{Path: "containers[app].image", Desired: "web:1.0", Live: "web:1.1"},
+				{Path: "containers[app].env[MODE]", Desired: "prod", Live: "debug"},
+			},
+		},
+		{
+			name:    "container and volume only on one side",
+			desired: deployment(nil),
+			live: deployment(func(d *appsv1.Deployment) {
+				spec := &d.Spec.Template.Spec
+				spec.Containers = spec.Containers[:1]
+				spec.Volumes = append(spec.Volumes, corev1.Volume{Name: "tls", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "web-tls"}}})
+			}),
+			want: []FieldDiff{
+				{Path: "containers[proxy]", Desired: "envoy:1.30"},
+				{Path: "volumes[tls]", Live: "secret/web-tls"},
+			},
+		},
+		{
+			name:    "defaulted service fields",
+			desired: service(nil),
+			live: service(func(s *corev1.Service) {
+				s.Spec.Type = corev1.ServiceTypeClusterIP
+				s.Spec.ClusterIP = "10.0.0.1"
+				s.Spec.Ports[1].TargetPort = intstr.FromInt32(9090)
+				s.Spec.Ports[0].Protocol = corev1.ProtocolTCP
+				s.Spec.Ports[1].Protocol = corev1.ProtocolTCP
+			}),
+		},
+		{
+			name:    "service port ordering",
+			desired: service(nil),
+			live: service(func(s *corev1.Service) {
+				s.Spec.Type = corev1.ServiceTypeClusterIP
+				s.Spec.Ports[0], s.Spec.Ports[1] = s.Spec.Ports[1], s.Spec.Ports[0]
+				s.Spec.Ports[0].TargetPort = intstr.FromInt32(9090)
+			}),
+		},
+		{
+			name:    "service changed",
+			desired: service(nil),
+			live: service(func(s *corev1.Service) {
+				s.Spec.Type = corev1.ServiceTypeNodePort
+				s.Spec.Ports[0].TargetPort = intstr.FromInt32(8081)
+				s.Spec.Ports[1].TargetPort = intstr.FromInt32(9090)
+			}),
+			want: []FieldDiff{
+				{Path: "type", Desired: "ClusterIP", Live: "NodePort"},
+				{Path: "ports[http]", Desired: "80->8080", Live: "80->8081"},
+			},
+		},
+		{
+			name:    "configmap keys",
+			desired: &corev1.ConfigMap{Data: map[string]string{"level": "info", "format": "json"}},
+			live:    &corev1.ConfigMap{Data: map[string]string{"level": "debug", "extra": "1", "format": "json"}},
+			want: []FieldDiff{
+				{Path: "data[extra]", Live: "1"},
+				{Path: "data[level]", Desired: "info", Live: "debug"},
+			},
+		},
+		{
+			name:    "secret keys",
+			desired: &corev1.Secret{Data: map[string][]byte{"password": []byte("hunter2"), "user": []byte("admin")}},
+			live:    &corev1.Secret{Type: corev1.SecretTypeOpaque, Data: map[string][]byte{"password": []byte("hunter3"), "user": []byte("admin"), "token": []byte("x")}},
+			want: []FieldDiff{
+				{Path: "data[password]", Desired: "set", Live: "changed"},
+				{Path: "data[token]", Live: "set"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DiffObjects(tt.desired, tt.live)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffObjects() = %+v, want %+v", got, tt.want)
+			}
+			for _, diff := range got {
+				if strings.Contains(diff.Desired+diff.Live, "hunter") {
+					t.Errorf("DiffObjects() shows a secret value in %+v", diff)
+				}
+			}
+		})
+	}
+}
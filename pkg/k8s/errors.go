@@ -0,0 +1,44 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Reasons a request to the cluster failed, from ErrorReason
+const (
+	ReasonNotFound     = "NotFound"
+	ReasonUnauthorized = "Unauthorized" // Missing or rejected credentials
+	ReasonForbidden    = "Forbidden"    // Authenticated but not allowed
+	ReasonTimeout      = "Timeout"
+	ReasonUnreachable  = "Unreachable" // The API server could not be connected to
+	ReasonUnknown      = "Unknown"
+)
+
+// ErrorReason classifies an error returned by the client, looking through
+// any wrapping, so callers can tell a cluster that cannot be reached from one
+// that refused the request
+func ErrorReason(err error) string {
+	var netErr net.Error
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	switch {
+	case apierrors.IsNotFound(err):
+		return ReasonNotFound
+	case apierrors.IsUnauthorized(err):
+		return ReasonUnauthorized
+	case apierrors.IsForbidden(err):
+		return ReasonForbidden
+	case apierrors.IsTimeout(err), apierrors.IsServerTimeout(err), errors.Is(err, context.DeadlineExceeded):
+		return ReasonTimeout
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return ReasonTimeout
+	case errors.As(err, &opErr), errors.As(err, &dnsErr):
+		return ReasonUnreachable
+	default:
+		return ReasonUnknown
+	}
+}
@@ -0,0 +1,87 @@
+package k8s
+
+import (
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Markers Argo CD and Flux leave on the objects they apply
+const (
+	argoTrackingIDAnnotation = "argocd.argoproj.io/tracking-id"
+	argoInstanceKey          = "argocd.argoproj.io/instance"
+	fluxKustomizeNameLabel   = "kustomize.toolkit.fluxcd.io/name"
+	fluxKustomizeNSLabel     = "kustomize.toolkit.fluxcd.io/namespace"
+	fluxHelmNameLabel        = "helm.toolkit.fluxcd.io/name"
+	fluxHelmNSLabel          = "helm.toolkit.fluxcd.io/namespace"
+)
+
+// ArgoCDNamespace is where Argo CD Applications live unless the tracking id
+// names another namespace
+const ArgoCDNamespace = "argocd"
+
+// GitOps owner kinds
+var (
+	ArgoApplication   = schema.GroupKind{Group: "argoproj.io", Kind: "Application"}
+	FluxKustomization = schema.GroupKind{Group: "kustomize.toolkit.fluxcd.io", Kind: "Kustomization"}
+	FluxHelmRelease   = schema.GroupKind{Group: "helm.toolkit.fluxcd.io", Kind: "HelmRelease"}
+)
+
+// GitOpsOwner identifies the Argo CD Application or Flux object that applied an object
+type GitOpsOwner struct {
+	Kind      schema.GroupKind
+	Namespace string
+	Name      string
+}
+
+// Key returns a string identifying the owner, unique across kinds and namespaces
+func (o *GitOpsOwner) Key() string {
+	return o.Kind.String() + "/" + o.Namespace + "/" + o.Name
+}
+
+// GitOpsOwnerOf returns the GitOps owner of an object, or nil. A Flux
+// HelmRelease is preferred over the Kustomization that applied it, and Flux
+// over Argo CD, as the more specific owner.
+func GitOpsOwnerOf(obj metav1.Object) *GitOpsOwner {
+	labels := obj.GetLabels()
+	annotations := obj.GetAnnotations()
+
+	if name := labels[fluxHelmNameLabel]; name != "" {
+		return newFluxOwner(FluxHelmRelease, labels[fluxHelmNSLabel], name, obj)
+	}
+	if name := labels[fluxKustomizeNameLabel]; name != "" {
+		return newFluxOwner(FluxKustomization, labels[fluxKustomizeNSLabel], name, obj)
+	}
+
+	// The tracking id is <app>:<group>/<kind>:<namespace>/<name>, where the app
+	// is <namespace>_<name> for Applications outside the Argo CD namespace
+	if id := annotations[argoTrackingIDAnnotation]; id != "" {
+		app, _, _ := strings.Cut(id, ":")
+		return newArgoOwner(app)
+	}
+	if app := annotations[argoInstanceKey]; app != "" {
+		return newArgoOwner(app)
+	}
+	if app := labels[argoInstanceKey]; app != "" {
+		return newArgoOwner(app)
+	}
+	return nil
+}
+
+// newFluxOwner creates a Flux owner, which defaults to the object's namespace
+func newFluxOwner(kind schema.GroupKind, namespace, name string, obj metav1.Object) *GitOpsOwner {
+	if namespace == "" {
+		namespace = obj.GetNamespace()
+	}
+	return &GitOpsOwner{Kind: kind, Namespace: namespace, Name: name}
+}
+
+// newArgoOwner parses an Argo CD application name, optionally namespace-qualified
+func newArgoOwner(app string) *GitOpsOwner {
+	namespace, name, ok := strings.Cut(app, "_")
+	if !ok {
+		namespace, name = ArgoCDNamespace, app
+	}
+	return &GitOpsOwner{Kind: ArgoApplication, Namespace: namespace, Name: name}
+}
@@ -0,0 +1,127 @@
+package k8s
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Markers Helm 3 leaves on the objects of a release
+const (
+	helmReleaseNameAnnotation = "meta.helm.sh/release-name"
+	managedByLabel            = "app.kubernetes.io/managed-by"
+	instanceLabel             = "app.kubernetes.io/instance"
+	helmReleaseSecretType     = "helm.sh/release.v1"
+)
+
+// HelmRelease is the latest revision of a Helm release, decoded from its
+// release Secret
+type HelmRelease struct {
+	Name         string
+	Chart        string
+	ChartVersion string
+	AppVersion   string
+	Status       string
+	Revision     int
+	Secret       *corev1.Secret // The release Secret of the latest revision
+}
+
+// helmReleaseData is the part of Helm's stored release record that is shown
+type helmReleaseData struct {
+	Info struct {
+		Status string `json:"status"`
+	} `json:"info"`
+	Chart struct {
+		Metadata struct {
+			Name       string `json:"name"`
+			Version    string `json:"version"`
+			AppVersion string `json:"appVersion"`
+		} `json:"metadata"`
+	} `json:"chart"`
+}
+
+// GetHelmReleases returns the latest revision of each Helm release in the
+// namespace, keyed by release name. Release Secrets are named
+// sh.helm.release.v1.<release>.v<revision> and labelled with name, version
+// and status; the chart comes from the encoded release record.
+func (r *Resources) GetHelmReleases() map[string]*HelmRelease {
+	releases := make(map[string]*HelmRelease)
+	for i := range r.Secrets.Items {
+		secret := &r.Secrets.Items[i]
+		if secret.Type != helmReleaseSecretType || secret.Labels["owner"] != "helm" {
+			continue
+		}
+
+		name := secret.Labels["name"]
+		revision, err := strconv.Atoi(secret.Labels["version"])
+		if name == "" || err != nil {
+			continue
+		}
+		if latest, ok := releases[name]; ok && latest.Revision >= revision {
+			continue
+		}
+
+		release := &HelmRelease{
+			Name:     name,
+			Status:   secret.Labels["status"],
+			Revision: revision,
+			Secret:   secret,
+		}
+		if data, err := decodeHelmRelease(secret.Data["release"]); err == nil {
+			release.Chart = data.Chart.Metadata.Name
+			release.ChartVersion = data.Chart.Metadata.Version
+			release.AppVersion = data.Chart.Metadata.AppVersion
+			if data.Info.Status != "" {
+				release.Status = data.Info.Status
+			}
+		}
+		releases[name] = release
+	}
+	return releases
+}
+
+// decodeHelmRelease decodes a release record, which Helm stores as base64 of
+// gzipped JSON inside the Secret data
+func decodeHelmRelease(encoded []byte) (*helmReleaseData, error) {
+	raw, err := base64.StdEncoding.DecodeString(string(encoded))
+	if err != nil {
+		return nil, err
+	}
+
+	// Older releases may not be compressed
+	if bytes.HasPrefix(raw, []byte{0x1f, 0x8b}) {
+		reader, err := gzip.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		if raw, err = io.ReadAll(reader); err != nil {
+			return nil, err
+		}
+	}
+
+	data := &helmReleaseData{}
+	if err := json.Unmarshal(raw, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// HelmReleaseName returns the Helm release that manages an object, or an empty
+// string. Helm 3 annotates every object it installs; objects that only carry
+// the managed-by label are matched by their instance label.
+func HelmReleaseName(obj metav1.Object) string {
+	if name := obj.GetAnnotations()[helmReleaseNameAnnotation]; name != "" {
+		return name
+	}
+	if obj.GetLabels()[managedByLabel] == "Helm" {
+		return obj.GetLabels()[instanceLabel]
+	}
+	return ""
+}
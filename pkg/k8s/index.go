@@ -0,0 +1,266 @@
+package k8s
+
+import (
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// namespacedOwnerKinds are the kinds fetched by GetResources that own other objects
+var namespacedOwnerKinds = []schema.GroupKind{
+	{Group: "apps", Kind: "Deployment"},
+	{Group: "apps", Kind: "ReplicaSet"},
+	{Group: "apps", Kind: "StatefulSet"},
+	{Group: "apps", Kind: "DaemonSet"},
+	{Group: "batch", Kind: "Job"},
+	{Group: "batch", Kind: "CronJob"},
+	{Group: "", Kind: "ReplicationController"},
+}
+
+// resourceIndex holds lookups over the fetched lists so that building a tree
+// takes linear time. Slices keep the order of the original lists.
+type resourceIndex struct {
+	// Dependents keyed by the UID of their controller
+	podsByOwner        map[types.UID][]*corev1.Pod
+	replicaSetsByOwner map[types.UID][]*appsv1.ReplicaSet
+	jobsByOwner        map[types.UID][]*batchv1.Job
+	revisionsByOwner   map[types.UID][]*appsv1.ControllerRevision
+
+	// Objects keyed by name
+	services          map[string]*corev1.Service
+	configMaps        map[string]*corev1.ConfigMap
+	secrets           map[string]*corev1.Secret
+	pvcs              map[string]*corev1.PersistentVolumeClaim
+	nodes             map[string]*corev1.Node
+	persistentVolumes map[string]*corev1.PersistentVolume
+	storageClasses    map[string]*storagev1.StorageClass
+
+	// PVCs named <prefix><ordinal>, keyed by prefix including the trailing dash
+	pvcsByPrefix map[string][]*corev1.PersistentVolumeClaim
+
+	endpointSlicesByService map[string][]*discoveryv1.EndpointSlice
+	volumeAttachmentsByPV   map[string][]*storagev1.VolumeAttachment
+
+	// Pods keyed by each of their labels, and Services keyed by each pair in
+	// their selector, both as "key=value"
+	podsByLabel        map[string][]*corev1.Pod
+	servicesBySelector map[string][]*corev1.Service
+
+	// UIDs of objects that can own others, by kind and then name
+	owners map[schema.GroupKind]map[string][]types.UID
+
+	// Objects of any kind by name, filled per kind on first lookup
+	objectsByKind map[schema.GroupKind]map[string]metav1.Object
+}
+
+// index returns the lookups over the fetched resources, building them on first use
+func (r *Resources) index() *resourceIndex {
+	if r.idx == nil {
+		r.idx = r.buildIndex()
+	}
+	return r.idx
+}
+
+// buildIndex walks every list once
+func (r *Resources) buildIndex() *resourceIndex {
+	idx := &resourceIndex{
+		podsByOwner:             make(map[types.UID][]*corev1.Pod),
+		replicaSetsByOwner:      make(map[types.UID][]*appsv1.ReplicaSet),
+		jobsByOwner:             make(map[types.UID][]*batchv1.Job),
+		revisionsByOwner:        make(map[types.UID][]*appsv1.ControllerRevision),
+		services:                make(map[string]*corev1.Service),
+		configMaps:              make(map[string]*corev1.ConfigMap),
+		secrets:                 make(map[string]*corev1.Secret),
+		pvcs:                    make(map[string]*corev1.PersistentVolumeClaim),
+		nodes:                   make(map[string]*corev1.Node),
+		persistentVolumes:       make(map[string]*corev1.PersistentVolume),
+		storageClasses:          make(map[string]*storagev1.StorageClass),
+		pvcsByPrefix:            make(map[string][]*corev1.PersistentVolumeClaim),
+		endpointSlicesByService: make(map[string][]*discoveryv1.EndpointSlice),
+		volumeAttachmentsByPV:   make(map[string][]*storagev1.VolumeAttachment),
+		podsByLabel:             make(map[string][]*corev1.Pod),
+		servicesBySelector:      make(map[string][]*corev1.Service),
+		owners:                  make(map[schema.GroupKind]map[string][]types.UID),
+		objectsByKind:           make(map[schema.GroupKind]map[string]metav1.Object),
+	}
+
+	for i := range r.Pods.Items {
+		pod := &r.Pods.Items[i]
+		if owner := metav1.GetControllerOfNoCopy(pod); owner != nil {
+			idx.podsByOwner[owner.UID] = append(idx.podsByOwner[owner.UID], pod)
+		}
+		for key, value := range pod.Labels {
+			idx.podsByLabel[key+"="+value] = append(idx.podsByLabel[key+"="+value], pod)
+		}
+	}
+	for i := range r.ReplicaSets.Items {
+		rs := &r.ReplicaSets.Items[i]
+		if owner := metav1.GetControllerOfNoCopy(rs); owner != nil {
+			idx.replicaSetsByOwner[owner.UID] = append(idx.replicaSetsByOwner[owner.UID], rs)
+		}
+		idx.addOwner("apps", "ReplicaSet", rs)
+	}
+	for i := range r.Jobs.Items {
+		job := &r.Jobs.Items[i]
+		if owner := metav1.GetControllerOfNoCopy(job); owner != nil {
+			idx.jobsByOwner[owner.UID] = append(idx.jobsByOwner[owner.UID], job)
+		}
+		idx.addOwner("batch", "Job", job)
+	}
+	for i := range r.ControllerRevisions.Items {
+		cr := &r.ControllerRevisions.Items[i]
+		if owner := metav1.GetControllerOfNoCopy(cr); owner != nil {
+			idx.revisionsByOwner[owner.UID] = append(idx.revisionsByOwner[owner.UID], cr)
+		}
+	}
+
+	// Every namespaced owner kind is fetched, so it is known even when empty
+	// and references to it can be reported missing
+	for _, gk := range namespacedOwnerKinds {
+		idx.ownersOf(gk.Group, gk.Kind)
+	}
+	for i := range r.Deployments.Items {
+		idx.addOwner("apps", "Deployment", &r.Deployments.Items[i])
+	}
+	for i := range r.StatefulSets.Items {
+		idx.addOwner("apps", "StatefulSet", &r.StatefulSets.Items[i])
+	}
+	for i := range r.DaemonSets.Items {
+		idx.addOwner("apps", "DaemonSet", &r.DaemonSets.Items[i])
+	}
+	for i := range r.CronJobs.Items {
+		idx.addOwner("batch", "CronJob", &r.CronJobs.Items[i])
+	}
+	for i := range r.ReplicationControllers.Items {
+		idx.addOwner("", "ReplicationController", &r.ReplicationControllers.Items[i])
+	}
+
+	// Nodes are only known when the caller may list them
+	for i := range r.Nodes.Items {
+		node := &r.Nodes.Items[i]
+		idx.nodes[node.Name] = node
+		idx.addOwner("", "Node", node)
+	}
+
+	for i := range r.ConfigMaps.Items {
+		idx.configMaps[r.ConfigMaps.Items[i].Name] = &r.ConfigMaps.Items[i]
+	}
+	for i := range r.Secrets.Items {
+		idx.secrets[r.Secrets.Items[i].Name] = &r.Secrets.Items[i]
+	}
+	for i := range r.PVCs.Items {
+		pvc := &r.PVCs.Items[i]
+		idx.pvcs[pvc.Name] = pvc
+		if dash := strings.LastIndex(pvc.Name, "-"); dash >= 0 {
+			prefix := pvc.Name[:dash+1]
+			idx.pvcsByPrefix[prefix] = append(idx.pvcsByPrefix[prefix], pvc)
+		}
+	}
+	for i := range r.PersistentVolumes.Items {
+		idx.persistentVolumes[r.PersistentVolumes.Items[i].Name] = &r.PersistentVolumes.Items[i]
+	}
+	for i := range r.StorageClasses.Items {
+		idx.storageClasses[r.StorageClasses.Items[i].Name] = &r.StorageClasses.Items[i]
+	}
+
+	for i := range r.EndpointSlices.Items {
+		slice := &r.EndpointSlices.Items[i]
+		if name, ok := slice.Labels[discoveryv1.LabelServiceName]; ok {
+			idx.endpointSlicesByService[name] = append(idx.endpointSlicesByService[name], slice)
+		}
+	}
+	for i := range r.VolumeAttachments.Items {
+		va := &r.VolumeAttachments.Items[i]
+		if pvName := va.Spec.Source.PersistentVolumeName; pvName != nil {
+			idx.volumeAttachmentsByPV[*pvName] = append(idx.volumeAttachmentsByPV[*pvName], va)
+		}
+	}
+
+	for i := range r.Services.Items {
+		svc := &r.Services.Items[i]
+		idx.services[svc.Name] = svc
+		for key, value := range svc.Spec.Selector {
+			idx.servicesBySelector[key+"="+value] = append(idx.servicesBySelector[key+"="+value], svc)
+		}
+	}
+
+	return idx
+}
+
+// ownersOf returns the name map for an owner kind, creating it if needed
+func (idx *resourceIndex) ownersOf(group, kind string) map[string][]types.UID {
+	gk := schema.GroupKind{Group: group, Kind: kind}
+	if idx.owners[gk] == nil {
+		idx.owners[gk] = make(map[string][]types.UID)
+	}
+	return idx.owners[gk]
+}
+
+// addOwner records an object that owner references may point at
+func (idx *resourceIndex) addOwner(group, kind string, obj metav1.Object) {
+	names := idx.ownersOf(group, kind)
+	names[obj.GetName()] = append(names[obj.GetName()], obj.GetUID())
+}
+
+// PodsMatchingSelector returns the pods whose labels include every pair in the selector
+func (r *Resources) PodsMatchingSelector(selector map[string]string) []*corev1.Pod {
+	if len(selector) == 0 {
+		return nil
+	}
+
+	// Start from the smallest candidate list, then check the remaining pairs
+	idx := r.index()
+	var candidates []*corev1.Pod
+	first := true
+	for key, value := range selector {
+		pods := idx.podsByLabel[key+"="+value]
+		if first || len(pods) < len(candidates) {
+			candidates = pods
+			first = false
+		}
+	}
+
+	var pods []*corev1.Pod
+	for _, pod := range candidates {
+		if labelsInclude(pod.Labels, selector) {
+			pods = append(pods, pod)
+		}
+	}
+	return pods
+}
+
+// ServicesSelecting returns the Services whose selector matches the given labels
+func (r *Resources) ServicesSelecting(podLabels map[string]string) []*corev1.Service {
+	idx := r.index()
+	seen := make(map[*corev1.Service]bool)
+	var services []*corev1.Service
+	for key, value := range podLabels {
+		for _, svc := range idx.servicesBySelector[key+"="+value] {
+			if seen[svc] {
+				continue
+			}
+			seen[svc] = true
+			if labelsInclude(podLabels, svc.Spec.Selector) {
+				services = append(services, svc)
+			}
+		}
+	}
+	return services
+}
+
+// labelsInclude reports whether labels contain every pair in the selector
+func labelsInclude(labels, selector map[string]string) bool {
+	for key, value := range selector {
+		if v, ok := labels[key]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
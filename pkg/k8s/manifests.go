@@ -0,0 +1,193 @@
+package k8s
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// LoadManifests reads the objects in a manifest file, or in every .yaml, .yml
+// and .json file under a directory. Files may hold several YAML documents,
+// and List objects are expanded into their items.
+func LoadManifests(path string) ([]*unstructured.Unstructured, error) {
+	var files []string
+	err := filepath.WalkDir(path, func(file string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".yaml", ".yml", ".json":
+			if !entry.IsDir() {
+				files = append(files, file)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading manifests: %v", err)
+	}
+
+	var objects []*unstructured.Unstructured
+	for _, file := range files {
+		fileObjects, err := loadManifestFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %v", file, err)
+		}
+		objects = append(objects, fileObjects...)
+	}
+	return objects, nil
+}
+
+// loadManifestFile decodes every document in a manifest file
+func loadManifestFile(file string) ([]*unstructured.Unstructured, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var objects []*unstructured.Unstructured
+	decoder := yaml.NewYAMLOrJSONDecoder(f, 4096)
+	for {
+		doc := make(map[string]interface{})
+		if err := decoder.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				return objects, nil
+			}
+			return nil, err
+		}
+		if len(doc) == 0 {
+			continue // Empty document between separators
+		}
+
+		obj := &unstructured.Unstructured{Object: doc}
+		if !obj.IsList() {
+			objects = append(objects, obj)
+			continue
+		}
+		err := obj.EachListItem(func(item runtime.Object) error {
+			objects = append(objects, item.(*unstructured.Unstructured))
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// ManifestResources sorts manifest objects into Resources as if they had been
+// fetched from the namespace, so the Builder can make a tree from them.
+// Objects without a namespace are placed in it and objects for other
+// namespaces are left out, as are kinds the tree does not show. Each object
+// gets a stand-in UID, and Secret stringData is folded into data as the API
+// server would.
+func ManifestResources(objects []*unstructured.Unstructured, namespace string) (*Resources, error) {
+	r := &Resources{
+		Services:               &corev1.ServiceList{},
+		EndpointSlices:         &discoveryv1.EndpointSliceList{},
+		ConfigMaps:             &corev1.ConfigMapList{},
+		Secrets:                &corev1.SecretList{},
+		PVCs:                   &corev1.PersistentVolumeClaimList{},
+		Pods:                   &corev1.PodList{},
+		Deployments:            &appsv1.DeploymentList{},
+		StatefulSets:           &appsv1.StatefulSetList{},
+		DaemonSets:             &appsv1.DaemonSetList{},
+		ReplicaSets:            &appsv1.ReplicaSetList{},
+		Jobs:                   &batchv1.JobList{},
+		CronJobs:               &batchv1.CronJobList{},
+		ControllerRevisions:    &appsv1.ControllerRevisionList{},
+		ReplicationControllers: &corev1.ReplicationControllerList{},
+		Nodes:                  &corev1.NodeList{},
+		PersistentVolumes:      &corev1.PersistentVolumeList{},
+		StorageClasses:         &storagev1.StorageClassList{},
+		VolumeAttachments:      &storagev1.VolumeAttachmentList{},
+	}
+
+	for _, obj := range objects {
+		obj = obj.DeepCopy()
+		if obj.GetNamespace() == "" {
+			obj.SetNamespace(namespace)
+		} else if obj.GetNamespace() != namespace {
+			continue
+		}
+		obj.SetUID(types.UID("manifest/" + obj.GetKind() + "/" + obj.GetName()))
+
+		var err error
+		switch obj.GroupVersionKind().GroupKind() {
+		case schema.GroupKind{Kind: "Service"}:
+			var svc corev1.Service
+			err = fromUnstructured(obj, &svc)
+			r.Services.Items = append(r.Services.Items, svc)
+		case schema.GroupKind{Kind: "ConfigMap"}:
+			var cm corev1.ConfigMap
+			err = fromUnstructured(obj, &cm)
+			r.ConfigMaps.Items = append(r.ConfigMaps.Items, cm)
+		case schema.GroupKind{Kind: "Secret"}:
+			var secret corev1.Secret
+			err = fromUnstructured(obj, &secret)
+			for key, value := range secret.StringData {
+				if secret.Data == nil {
+					secret.Data = make(map[string][]byte)
+				}
+				secret.Data[key] = []byte(value)
+			}
+			secret.StringData = nil
+			r.Secrets.Items = append(r.Secrets.Items, secret)
+		case schema.GroupKind{Kind: "PersistentVolumeClaim"}:
+			var pvc corev1.PersistentVolumeClaim
+			err = fromUnstructured(obj, &pvc)
+			r.PVCs.Items = append(r.PVCs.Items, pvc)
+		case schema.GroupKind{Kind: "Pod"}:
+			var pod corev1.Pod
+			err = fromUnstructured(obj, &pod)
+			r.Pods.Items = append(r.Pods.Items, pod)
+		case schema.GroupKind{Kind: "ReplicationController"}:
+			var rc corev1.ReplicationController
+			err = fromUnstructured(obj, &rc)
+			r.ReplicationControllers.Items = append(r.ReplicationControllers.Items, rc)
+		case schema.GroupKind{Group: "apps", Kind: "Deployment"}:
+			var dep appsv1.Deployment
+			err = fromUnstructured(obj, &dep)
+			r.Deployments.Items = append(r.Deployments.Items, dep)
+		case schema.GroupKind{Group: "apps", Kind: "StatefulSet"}:
+			var sts appsv1.StatefulSet
+			err = fromUnstructured(obj, &sts)
+			r.StatefulSets.Items = append(r.StatefulSets.Items, sts)
+		case schema.GroupKind{Group: "apps", Kind: "DaemonSet"}:
+			var ds appsv1.DaemonSet
+			err = fromUnstructured(obj, &ds)
+			r.DaemonSets.Items = append(r.DaemonSets.Items, ds)
+		case schema.GroupKind{Group: "batch", Kind: "Job"}:
+			var job batchv1.Job
+			err = fromUnstructured(obj, &job)
+			r.Jobs.Items = append(r.Jobs.Items, job)
+		case schema.GroupKind{Group: "batch", Kind: "CronJob"}:
+			var cronJob batchv1.CronJob
+			err = fromUnstructured(obj, &cronJob)
+			r.CronJobs.Items = append(r.CronJobs.Items, cronJob)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error decoding %s %s: %v", obj.GetKind(), obj.GetName(), err)
+		}
+	}
+	return r, nil
+}
+
+// fromUnstructured converts a manifest object into its typed form
+func fromUnstructured(obj *unstructured.Unstructured, into interface{}) error {
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, into)
+}
@@ -0,0 +1,135 @@
+package k8s
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestLoadManifests(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		path  string // Relative to the directory holding the files, "" for the directory itself
+		want  []string
+	}{
+		{
+			name: "multiple documents",
+			files: map[string]string{
+				"app.yaml": "---\napiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n---\n\n---\napiVersion: v1\nkind: Service\nmetadata:\n  name: web\n",
+			},
+			path: "app.yaml",
+			want: []string{"Deployment/web", "Service/web"},
+		},
+		{
+			name: "list",
+			files: map[string]string{
+				"list.yaml": "apiVersion: v1\nkind: List\nitems:\n- apiVersion: v1\n  kind: ConfigMap\n  metadata:\n    name: a\n- apiVersion: v1\n  kind: Secret\n  metadata:\n    name: b\n",
+			},
+			path: "list.yaml",
+			want: []string{"ConfigMap/a", "Secret/b"},
+		},
+		{
+			name: "json",
+			files: map[string]string{
+				"cm.json": `{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "settings"}}`,
+			},
+			path: "cm.json",
+			want: []string{"ConfigMap/settings"},
+		},
+		{
+			name: "directory",
+			files: map[string]string{
+				"a.yaml":         "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n",
+				"b/c.yml":        "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: c\n",
+				"b/d.json":       `{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "d"}}`,
+				"README.md":      "# not a manifest\n",
+				"kustomize.txt":  "kind: ConfigMap\n",
+				"b/values.YAML":  "apiVersion: v1\nkind: Secret\nmetadata:\n  name: e\n",
+				"b/empty.yaml":   "",
+				"b/comment.yaml": "# only a comment\n",
+			},
+			want: []string{"ConfigMap/a", "ConfigMap/c", "ConfigMap/d", "Secret/e"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				file := filepath.Join(dir, name)
+				if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			objects, err := LoadManifests(filepath.Join(dir, tt.path))
+			if err != nil {
+				t.Fatalf("LoadManifests() error = %v", err)
+			}
+			var got []string
+			for _, obj := range objects {
+				got = append(got, obj.GetKind()+"/"+obj.GetName())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LoadManifests() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadManifestsInvalid(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "bad.yaml")
+	if err := os.WriteFile(file, []byte("kind: [unclosed\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadManifests(file); err == nil {
+		t.Error("LoadManifests() with invalid YAML returned no error")
+	}
+	if _, err := LoadManifests(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("LoadManifests() with a missing path returned no error")
+	}
+}
+
+func TestManifestResources(t *testing.T) {
+	secret := manifestObject("v1", "Secret", "db", nil)
+	secret.Object["stringData"] = map[string]interface{}{"password": "hunter2"}
+	other := manifestObject("v1", "ConfigMap", "other", nil)
+	other.SetNamespace("elsewhere")
+
+	resources, err := ManifestResources([]*unstructured.Unstructured{
+		manifestObject("apps/v1", "Deployment", "web", nil),
+		secret,
+		other,
+		manifestObject("networking.k8s.io/v1", "Ingress", "web", nil),
+	}, "ns")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(resources.Deployments.Items) != 1 || resources.Deployments.Items[0].Namespace != "ns" {
+		t.Errorf("Deployments = %+v, want web placed in ns", resources.Deployments.Items)
+	}
+	if len(resources.ConfigMaps.Items) != 0 {
+		t.Errorf("ConfigMaps = %+v, want the one in another namespace left out", resources.ConfigMaps.Items)
+	}
+	if len(resources.Secrets.Items) != 1 {
+		t.Fatalf("Secrets = %+v, want db", resources.Secrets.Items)
+	}
+	got := resources.Secrets.Items[0]
+	if string(got.Data["password"]) != "hunter2" || got.StringData != nil {
+		t.Errorf("Secret data = %v, stringData = %v, want stringData folded into data", got.Data, got.StringData)
+	}
+	if got.UID == "" || got.UID == resources.Deployments.Items[0].UID {
+		t.Errorf("Secret UID = %q, want a distinct stand-in UID", got.UID)
+	}
+	if secret.Object["stringData"] == nil {
+		t.Error("ManifestResources() changed the object it was given")
+	}
+}
@@ -0,0 +1,344 @@
+package k8s
+
+import (
+	"sort"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// defaultRevisionHistoryLimit is used when a Deployment does not set one
+const defaultRevisionHistoryLimit = 10
+
+// systemConfigMaps are published into every namespace by the control plane
+var systemConfigMaps = map[string]bool{
+	"kube-root-ca.crt": true,
+}
+
+// systemSecretTypes are secret types managed by Kubernetes or tooling rather than workloads
+var systemSecretTypes = map[corev1.SecretType]bool{
+	corev1.SecretTypeServiceAccountToken: true,
+	corev1.SecretTypeBootstrapToken:      true,
+	"helm.sh/release.v1":                 true,
+}
+
+// Orphans holds resources that are not referenced by any workload or pod
+type Orphans struct {
+	ConfigMaps  []*corev1.ConfigMap
+	Secrets     []*corev1.Secret
+	PVCs        []*corev1.PersistentVolumeClaim
+	Services    []*corev1.Service
+	ReplicaSets []*appsv1.ReplicaSet
+}
+
+// IsEmpty returns true if no orphans were found
+func (o *Orphans) IsEmpty() bool {
+	return len(o.ConfigMaps) == 0 &&
+		len(o.Secrets) == 0 &&
+		len(o.PVCs) == 0 &&
+		len(o.Services) == 0 &&
+		len(o.ReplicaSets) == 0
+}
+
+// references collects the names of resources used by pod specs and how each
+// is used
+type references struct {
+	configMaps map[string]EdgeType
+	secrets    map[string]EdgeType
+	pvcs       map[string]EdgeType
+	services   map[string]EdgeType
+}
+
+// newReferences returns an empty set of references
+func newReferences() *references {
+	return &references{
+		configMaps: make(map[string]EdgeType),
+		secrets:    make(map[string]EdgeType),
+		pvcs:       make(map[string]EdgeType),
+		services:   make(map[string]EdgeType),
+	}
+}
+
+// addReference records a name unless it is already known, so a volume mount
+// is kept over a later env reference to the same object
+func addReference(names map[string]EdgeType, name string, edge EdgeType) {
+	if _, ok := names[name]; !ok {
+		names[name] = edge
+	}
+}
+
+// FindOrphans returns the ConfigMaps, Secrets, PVCs and Services that nothing
+// in the namespace references, plus ReplicaSets scaled to zero beyond their
+// Deployment's revisionHistoryLimit. Secrets named by Ingresses and
+// ServiceAccounts count as referenced when those lists were fetched, as do
+// objects that relationship rules reach, if rules is not nil. Resources owned
+// by another object and known system resources are never reported.
+func (r *Resources) FindOrphans(rules *RuleSet) *Orphans {
+	refs := r.collectReferences()
+	refs.addRuleEdges(rules, r)
+	orphans := &Orphans{}
+
+	for i, cm := range r.ConfigMaps.Items {
+		if refs.configMaps[cm.Name] != "" || systemConfigMaps[cm.Name] || len(cm.OwnerReferences) > 0 {
+			continue
+		}
+		orphans.ConfigMaps = append(orphans.ConfigMaps, &r.ConfigMaps.Items[i])
+	}
+
+	for i, secret := range r.Secrets.Items {
+		if refs.secrets[secret.Name] != "" || systemSecretTypes[secret.Type] || len(secret.OwnerReferences) > 0 {
+			continue
+		}
+		orphans.Secrets = append(orphans.Secrets, &r.Secrets.Items[i])
+	}
+
+	for i, pvc := range r.PVCs.Items {
+		if refs.pvcs[pvc.Name] != "" || len(pvc.OwnerReferences) > 0 {
+			continue
+		}
+		orphans.PVCs = append(orphans.PVCs, &r.PVCs.Items[i])
+	}
+
+	// Services without a selector have manually managed endpoints, so skip them.
+	// A Service selecting a workload scaled to zero is still in use.
+	templates := r.podTemplates()
+	for i, svc := range r.Services.Items {
+		if len(svc.Spec.Selector) == 0 || refs.services[svc.Name] != "" {
+			continue
+		}
+		if len(r.PodsMatchingSelector(svc.Spec.Selector)) == 0 && !templatesSelected(templates, svc.Spec.Selector) {
+			orphans.Services = append(orphans.Services, &r.Services.Items[i])
+		}
+	}
+
+	orphans.ReplicaSets = r.findStaleReplicaSets()
+
+	return orphans
+}
+
+// collectReferences gathers every ConfigMap, Secret and PVC name used by pods and workload templates
+func (r *Resources) collectReferences() *references {
+	refs := newReferences()
+
+	for i := range r.Pods.Items {
+		refs.addPodSpec(&r.Pods.Items[i].Spec)
+		refs.addPullSecrets(r.Pods.Items[i].Spec.ImagePullSecrets)
+	}
+	for _, template := range r.podTemplates() {
+		refs.addPodSpec(&template.Spec)
+		refs.addPullSecrets(template.Spec.ImagePullSecrets)
+	}
+	for i := range r.StatefulSets.Items {
+		sts := &r.StatefulSets.Items[i]
+
+		// PVCs kept from scaled-down ordinals are reused on scale up, so count them as referenced
+		byOrdinal, scaledDown := r.GetStatefulSetPVCs(sts)
+		for _, pvcs := range byOrdinal {
+			for _, pvc := range pvcs {
+				addReference(refs.pvcs, pvc.Name, EdgeMounts)
+			}
+		}
+		for _, pvc := range scaledDown {
+			addReference(refs.pvcs, pvc.Name, EdgeMounts)
+		}
+	}
+
+	// TLS certificates of Ingresses
+	if r.Ingresses != nil {
+		for _, ingress := range r.Ingresses.Items {
+			for _, tls := range ingress.Spec.TLS {
+				if tls.SecretName != "" {
+					addReference(refs.secrets, tls.SecretName, EdgeRefers)
+				}
+			}
+		}
+	}
+
+	// Pull secrets and mountable secrets of ServiceAccounts
+	if r.ServiceAccounts != nil {
+		for _, sa := range r.ServiceAccounts.Items {
+			refs.addPullSecrets(sa.ImagePullSecrets)
+			for _, secret := range sa.Secrets {
+				addReference(refs.secrets, secret.Name, EdgeRefers)
+			}
+		}
+	}
+
+	return refs
+}
+
+// addRuleEdges records the objects that relationship rules reach from every
+// object of their source kinds, e.g. the Secret named by a KafkaUser
+func (refs *references) addRuleEdges(rules *RuleSet, r *Resources) {
+	if rules == nil {
+		return
+	}
+
+	seen := make(map[schema.GroupKind]bool)
+	for _, rule := range rules.Rules {
+		if seen[rule.from] {
+			continue
+		}
+		seen[rule.from] = true
+
+		for _, obj := range r.ObjectsOfKind(rule.from) {
+			for _, edge := range rules.Resolve(obj, r) {
+				switch target := edge.Object.(type) {
+				case *corev1.ConfigMap:
+					addReference(refs.configMaps, target.Name, edge.Type)
+				case *corev1.Secret:
+					addReference(refs.secrets, target.Name, edge.Type)
+				case *corev1.PersistentVolumeClaim:
+					addReference(refs.pvcs, target.Name, edge.Type)
+				case *corev1.Service:
+					addReference(refs.services, target.Name, edge.Type)
+				}
+			}
+		}
+	}
+}
+
+// podTemplates returns the pod templates of every workload
+func (r *Resources) podTemplates() []*corev1.PodTemplateSpec {
+	var objects []metav1.Object
+	for i := range r.Deployments.Items {
+		objects = append(objects, &r.Deployments.Items[i])
+	}
+	for i := range r.StatefulSets.Items {
+		objects = append(objects, &r.StatefulSets.Items[i])
+	}
+	for i := range r.DaemonSets.Items {
+		objects = append(objects, &r.DaemonSets.Items[i])
+	}
+	for i := range r.ReplicaSets.Items {
+		objects = append(objects, &r.ReplicaSets.Items[i])
+	}
+	for i := range r.Jobs.Items {
+		objects = append(objects, &r.Jobs.Items[i])
+	}
+	for i := range r.CronJobs.Items {
+		objects = append(objects, &r.CronJobs.Items[i])
+	}
+	for i := range r.ReplicationControllers.Items {
+		objects = append(objects, &r.ReplicationControllers.Items[i])
+	}
+
+	var templates []*corev1.PodTemplateSpec
+	for _, obj := range objects {
+		if template := PodTemplateOf(obj); template != nil {
+			templates = append(templates, template)
+		}
+	}
+	return templates
+}
+
+// templatesSelected reports whether the selector matches the pod labels of any template
+func templatesSelected(templates []*corev1.PodTemplateSpec, selector map[string]string) bool {
+	for _, template := range templates {
+		if labelsInclude(template.Labels, selector) {
+			return true
+		}
+	}
+	return false
+}
+
+// addPodSpec records the resources a pod spec uses through volumes, including
+// projected ones, and the environment of every kind of container. Volumes are
+// walked first, so an object both mounted and used in env counts as mounted.
+func (refs *references) addPodSpec(podSpec *corev1.PodSpec) {
+	for _, vol := range podSpec.Volumes {
+		if vol.ConfigMap != nil {
+			addReference(refs.configMaps, vol.ConfigMap.Name, EdgeMounts)
+		}
+		if vol.Secret != nil {
+			addReference(refs.secrets, vol.Secret.SecretName, EdgeMounts)
+		}
+		if vol.PersistentVolumeClaim != nil {
+			addReference(refs.pvcs, vol.PersistentVolumeClaim.ClaimName, EdgeMounts)
+		}
+		if vol.Projected != nil {
+			for _, source := range vol.Projected.Sources {
+				if source.ConfigMap != nil {
+					addReference(refs.configMaps, source.ConfigMap.Name, EdgeMounts)
+				}
+				if source.Secret != nil {
+					addReference(refs.secrets, source.Secret.Name, EdgeMounts)
+				}
+			}
+		}
+	}
+
+	containers := append([]corev1.Container{}, podSpec.InitContainers...)
+	containers = append(containers, podSpec.Containers...)
+	for _, container := range containers {
+		refs.addEnv(container.EnvFrom, container.Env)
+	}
+	for _, container := range podSpec.EphemeralContainers {
+		refs.addEnv(container.EnvFrom, container.Env)
+	}
+}
+
+// addEnv records the resources referenced by envFrom and env entries
+func (refs *references) addEnv(envFrom []corev1.EnvFromSource, env []corev1.EnvVar) {
+	for _, source := range envFrom {
+		if source.ConfigMapRef != nil {
+			addReference(refs.configMaps, source.ConfigMapRef.Name, EdgeEnv)
+		}
+		if source.SecretRef != nil {
+			addReference(refs.secrets, source.SecretRef.Name, EdgeEnv)
+		}
+	}
+
+	for _, e := range env {
+		if e.ValueFrom == nil {
+			continue
+		}
+		if e.ValueFrom.ConfigMapKeyRef != nil {
+			addReference(refs.configMaps, e.ValueFrom.ConfigMapKeyRef.Name, EdgeEnv)
+		}
+		if e.ValueFrom.SecretKeyRef != nil {
+			addReference(refs.secrets, e.ValueFrom.SecretKeyRef.Name, EdgeEnv)
+		}
+	}
+}
+
+// addPullSecrets records image pull secrets. They are kept out of addPodSpec
+// as the tree does not show them under workloads.
+func (refs *references) addPullSecrets(pullSecrets []corev1.LocalObjectReference) {
+	for _, secret := range pullSecrets {
+		addReference(refs.secrets, secret.Name, EdgeRefers)
+	}
+}
+
+// findStaleReplicaSets returns ReplicaSets scaled to zero that are older than
+// their Deployment's revisionHistoryLimit
+func (r *Resources) findStaleReplicaSets() []*appsv1.ReplicaSet {
+	var stale []*appsv1.ReplicaSet
+
+	for i := range r.Deployments.Items {
+		dep := &r.Deployments.Items[i]
+		limit := defaultRevisionHistoryLimit
+		if dep.Spec.RevisionHistoryLimit != nil {
+			limit = int(*dep.Spec.RevisionHistoryLimit)
+		}
+
+		var scaledDown []*appsv1.ReplicaSet
+		for _, rs := range r.GetReplicaSetsByOwner(dep) {
+			if rs.Spec.Replicas != nil && *rs.Spec.Replicas == 0 {
+				scaledDown = append(scaledDown, rs)
+			}
+		}
+
+		// Newest revisions first, so everything past the limit is stale
+		sort.Slice(scaledDown, func(i, j int) bool {
+			return ReplicaSetRevision(scaledDown[i]) > ReplicaSetRevision(scaledDown[j])
+		})
+		if len(scaledDown) > limit {
+			stale = append(stale, scaledDown[limit:]...)
+		}
+	}
+
+	return stale
+}
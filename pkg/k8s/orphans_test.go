@@ -0,0 +1,258 @@
+package k8s
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// orphanNames returns the orphans as sorted Kind/name strings
+func orphanNames(orphans *Orphans) []string {
+	var names []string
+	for _, cm := range orphans.ConfigMaps {
+		names = append(names, "ConfigMap/"+cm.Name)
+	}
+	for _, secret := range orphans.Secrets {
+		names = append(names, "Secret/"+secret.Name)
+	}
+	for _, pvc := range orphans.PVCs {
+		names = append(names, "PersistentVolumeClaim/"+pvc.Name)
+	}
+	for _, svc := range orphans.Services {
+		names = append(names, "Service/"+svc.Name)
+	}
+	for _, rs := range orphans.ReplicaSets {
+		names = append(names, "ReplicaSet/"+rs.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestFindOrphans(t *testing.T) {
+	int32Ptr := func(n int32) *int32 { return &n }
+	configMap := func(name string) corev1.ConfigMap {
+		return corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	}
+	secret := func(name string, secretType corev1.SecretType) corev1.Secret {
+		return corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name}, Type: secretType}
+	}
+	pvc := func(name string) corev1.PersistentVolumeClaim {
+		return corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	}
+	// deployment returns a Deployment whose pods carry app=name
+	deployment := func(name string, replicas int32, spec corev1.PodSpec) appsv1.Deployment {
+		return appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: name, UID: types.UID(name)},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: int32Ptr(replicas),
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": name}},
+					Spec:       spec,
+				},
+			},
+		}
+	}
+	// replicaSet returns a ReplicaSet of the web Deployment at the given revision
+	replicaSet := func(revision int, replicas int32) appsv1.ReplicaSet {
+		controller := true
+		return appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "web-" + strconv.Itoa(revision),
+				Annotations:     map[string]string{revisionAnnotation: strconv.Itoa(revision)},
+				OwnerReferences: []metav1.OwnerReference{{Kind: "Deployment", Name: "web", UID: "web", Controller: &controller}},
+			},
+			Spec: appsv1.ReplicaSetSpec{Replicas: int32Ptr(replicas)},
+		}
+	}
+
+	tests := []struct {
+		name  string
+		setup func(r *Resources)
+		want  []string
+	}{
+		{
+			name: "unreferenced",
+			setup: func(r *Resources) {
+				r.ConfigMaps.Items = append(r.ConfigMaps.Items, configMap("config"))
+				r.Secrets.Items = append(r.Secrets.Items, secret("password", corev1.SecretTypeOpaque))
+				r.PVCs.Items = append(r.PVCs.Items, pvc("data"))
+			},
+			want: []string{"ConfigMap/config", "PersistentVolumeClaim/data", "Secret/password"},
+		},
+		{
+			name: "system resources",
+			setup: func(r *Resources) {
+				r.ConfigMaps.Items = append(r.ConfigMaps.Items, configMap("kube-root-ca.crt"))
+				r.Secrets.Items = append(r.Secrets.Items,
+					secret("default-token", corev1.SecretTypeServiceAccountToken),
+					secret("bootstrap-token-abc", corev1.SecretTypeBootstrapToken),
+					secret("sh.helm.release.v1.web.v1", "helm.sh/release.v1"),
+				)
+			},
+		},
+		{
+			name: "owned resources",
+			setup: func(r *Resources) {
+				owners := []metav1.OwnerReference{{Kind: "Certificate", Name: "web"}}
+				cm := configMap("generated")
+				cm.OwnerReferences = owners
+				tls := secret("web-tls", corev1.SecretTypeTLS)
+				tls.OwnerReferences = owners
+				claim := pvc("data")
+				claim.OwnerReferences = owners
+				r.ConfigMaps.Items = append(r.ConfigMaps.Items, cm)
+				r.Secrets.Items = append(r.Secrets.Items, tls)
+				r.PVCs.Items = append(r.PVCs.Items, claim)
+			},
+		},
+		{
+			name: "projected, init and ephemeral container references",
+			setup: func(r *Resources) {
+				r.ConfigMaps.Items = append(r.ConfigMaps.Items, configMap("projected"), configMap("init-env"), configMap("unused"))
+				r.Secrets.Items = append(r.Secrets.Items,
+					secret("projected", corev1.SecretTypeOpaque),
+					secret("debug-env", corev1.SecretTypeOpaque),
+					secret("pull", corev1.SecretTypeDockerConfigJson),
+				)
+				r.Pods.Items = append(r.Pods.Items, corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{Name: "web"},
+					Spec: corev1.PodSpec{
+						ImagePullSecrets: []corev1.LocalObjectReference{{Name: "pull"}},
+						Volumes: []corev1.Volume{{Name: "all", VolumeSource: corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{
+							Sources: []corev1.VolumeProjection{
+								{ConfigMap: &corev1.ConfigMapProjection{LocalObjectReference: corev1.LocalObjectReference{Name: "projected"}}},
+								{Secret: &corev1.SecretProjection{LocalObjectReference: corev1.LocalObjectReference{Name: "projected"}}},
+							},
+						}}}},
+						InitContainers: []corev1.Container{{Name: "init", EnvFrom: []corev1.EnvFromSource{
+							{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "init-env"}}},
+						}}},
+						EphemeralContainers: []corev1.EphemeralContainer{{EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+							Name: "debug",
+							Env: []corev1.EnvVar{{Name: "TOKEN", ValueFrom: &corev1.EnvVarSource{
+								SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "debug-env"}, Key: "token"},
+							}}},
+						}}},
+					},
+				})
+			},
+			want: []string{"ConfigMap/unused"},
+		},
+		{
+			name: "statefulset volume claim templates",
+			setup: func(r *Resources) {
+				r.StatefulSets.Items = append(r.StatefulSets.Items, appsv1.StatefulSet{
+					ObjectMeta: metav1.ObjectMeta{Name: "db"},
+					Spec: appsv1.StatefulSetSpec{
+						Replicas:             int32Ptr(1),
+						VolumeClaimTemplates: []corev1.PersistentVolumeClaim{pvc("data")},
+					},
+				})
+				// db-1 was kept when the StatefulSet was scaled down and is reused on scale up
+				r.PVCs.Items = append(r.PVCs.Items, pvc("data-db-0"), pvc("data-db-1"), pvc("data-other-0"))
+			},
+			want: []string{"PersistentVolumeClaim/data-other-0"},
+		},
+		{
+			name: "ingress tls and serviceaccount secrets",
+			setup: func(r *Resources) {
+				r.Secrets.Items = append(r.Secrets.Items,
+					secret("web-tls", corev1.SecretTypeTLS),
+					secret("registry", corev1.SecretTypeDockerConfigJson),
+					secret("mountable", corev1.SecretTypeOpaque),
+				)
+				r.Ingresses = &networkingv1.IngressList{Items: []networkingv1.Ingress{{
+					Spec: networkingv1.IngressSpec{TLS: []networkingv1.IngressTLS{{SecretName: "web-tls"}}},
+				}}}
+				r.ServiceAccounts = &corev1.ServiceAccountList{Items: []corev1.ServiceAccount{{
+					ImagePullSecrets: []corev1.LocalObjectReference{{Name: "registry"}},
+					Secrets:          []corev1.ObjectReference{{Name: "mountable"}},
+				}}}
+			},
+		},
+		{
+			name: "ingresses and serviceaccounts not fetched",
+			setup: func(r *Resources) {
+				r.Secrets.Items = append(r.Secrets.Items, secret("web-tls", corev1.SecretTypeTLS))
+			},
+			want: []string{"Secret/web-tls"},
+		},
+		{
+			name: "services",
+			setup: func(r *Resources) {
+				r.Deployments.Items = append(r.Deployments.Items, deployment("idle", 0, corev1.PodSpec{}))
+				r.Pods.Items = append(r.Pods.Items, corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-1", Labels: map[string]string{"app": "web"}}})
+				r.Services.Items = append(r.Services.Items,
+					corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "web"}, Spec: corev1.ServiceSpec{Selector: map[string]string{"app": "web"}}},
+					corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "idle"}, Spec: corev1.ServiceSpec{Selector: map[string]string{"app": "idle"}}},
+					corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "external"}},
+					corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "gone"}, Spec: corev1.ServiceSpec{Selector: map[string]string{"app": "gone"}}},
+				)
+			},
+			want: []string{"Service/gone"},
+		},
+		{
+			name: "stale replicasets",
+			setup: func(r *Resources) {
+				dep := deployment("web", 1, corev1.PodSpec{})
+				dep.Spec.RevisionHistoryLimit = int32Ptr(2)
+				r.Deployments.Items = append(r.Deployments.Items, dep)
+				r.ReplicaSets.Items = append(r.ReplicaSets.Items,
+					replicaSet(1, 0), replicaSet(2, 0), replicaSet(3, 0), replicaSet(4, 0), replicaSet(5, 1),
+				)
+			},
+			want: []string{"ReplicaSet/web-1", "ReplicaSet/web-2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resources, err := ManifestResources(nil, "ns")
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.setup(resources)
+
+			got := orphanNames(resources.FindOrphans(nil))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindOrphans() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindOrphansFollowsRules(t *testing.T) {
+	resources, err := ManifestResources([]*unstructured.Unstructured{
+		manifestObject("v1", "Secret", "kafka-user", nil),
+		manifestObject("v1", "Secret", "unused", nil),
+	}, "ns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kafkaUser := manifestObject("kafka.strimzi.io/v1beta2", "KafkaUser", "app", nil)
+	kafkaUser.Object["spec"] = map[string]interface{}{"secretRef": map[string]interface{}{"name": "kafka-user"}}
+	resources.Custom = map[schema.GroupKind][]*unstructured.Unstructured{
+		{Group: "kafka.strimzi.io", Kind: "KafkaUser"}: {kafkaUser},
+	}
+
+	rules := &RuleSet{Rules: []Rule{{From: "kafka.strimzi.io/KafkaUser", Path: "spec.secretRef.name", To: "Secret"}}}
+	if err := rules.Rules[0].parse(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := orphanNames(resources.FindOrphans(nil)), []string{"Secret/kafka-user", "Secret/unused"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FindOrphans(nil) = %q, want %q", got, want)
+	}
+	if got, want := orphanNames(resources.FindOrphans(rules)), []string{"Secret/unused"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FindOrphans(rules) = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,45 @@
+package k8s
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// OwnerState describes whether an owner reference still points at a live object
+type OwnerState int
+
+const (
+	// OwnerUnknown means the owner's kind is not fetched, so it cannot be checked
+	OwnerUnknown OwnerState = iota
+	// OwnerLive means the referenced object exists with the referenced UID
+	OwnerLive
+	// OwnerMissing means the owner was deleted and its dependents await garbage collection
+	OwnerMissing
+	// OwnerRecreated means an object of the same name exists but with a different UID
+	OwnerRecreated
+)
+
+// CheckOwner resolves an owner reference against the fetched resources by kind,
+// API group and name, then compares UIDs
+func (r *Resources) CheckOwner(ref metav1.OwnerReference) OwnerState {
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil {
+		return OwnerUnknown
+	}
+
+	names, ok := r.index().owners[schema.GroupKind{Group: gv.Group, Kind: ref.Kind}]
+	if !ok {
+		return OwnerUnknown
+	}
+
+	uids := names[ref.Name]
+	if len(uids) == 0 {
+		return OwnerMissing
+	}
+	for _, uid := range uids {
+		if uid == ref.UID {
+			return OwnerLive
+		}
+	}
+	return OwnerRecreated
+}
@@ -0,0 +1,209 @@
+package k8s
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EdgeType describes how an object is related to the object it was resolved from
+type EdgeType string
+
+const (
+	EdgeSelects EdgeType = "selects" // A Service whose selector matches the workload's pods
+	EdgeGoverns EdgeType = "governs" // A StatefulSet's governing Service
+	EdgeMounts  EdgeType = "mounts"  // A volume source used by the pod template
+	EdgeEnv     EdgeType = "env"     // A source of environment variables
+	EdgeGuess   EdgeType = "guess"   // Matched by name only, so possibly wrong
+)
+
+// Edge is a relationship from an object to a related object
+type Edge struct {
+	Type   EdgeType
+	Kind   string        // Kind of the related object; typed list items carry no TypeMeta
+	Object metav1.Object // The related object
+}
+
+// Resolver finds the objects related to a given object. Resolvers read from the
+// fetched resources and their lookups, and return nothing for objects they do
+// not handle.
+type Resolver interface {
+	Resolve(obj metav1.Object, resources *Resources) []Edge
+}
+
+// ResolverFunc adapts a function to the Resolver interface
+type ResolverFunc func(obj metav1.Object, resources *Resources) []Edge
+
+// Resolve calls f(obj, resources)
+func (f ResolverFunc) Resolve(obj metav1.Object, resources *Resources) []Edge {
+	return f(obj, resources)
+}
+
+type namedResolver struct {
+	name     string
+	resolver Resolver
+}
+
+var (
+	resolversMu sync.RWMutex
+	resolvers   []namedResolver
+)
+
+// RegisterResolver adds a resolver to the registry under a unique name. Edges
+// are returned in registration order, so built-in resolvers come first.
+// It panics if the name is already registered, as a duplicate is a programming error.
+func RegisterResolver(name string, resolver Resolver) {
+	resolversMu.Lock()
+	defer resolversMu.Unlock()
+
+	for _, r := range resolvers {
+		if r.name == name {
+			panic(fmt.Sprintf("resolver %q already registered", name))
+		}
+	}
+	resolvers = append(resolvers, namedResolver{name: name, resolver: resolver})
+}
+
+// RegisteredResolvers returns the names of all registered resolvers in order
+func RegisteredResolvers() []string {
+	resolversMu.RLock()
+	defer resolversMu.RUnlock()
+
+	names := make([]string, len(resolvers))
+	for i, r := range resolvers {
+		names[i] = r.name
+	}
+	return names
+}
+
+// Resolve runs every registered resolver on an object. Each related object is
+// returned once; an edge found by one resolver replaces a guess by another.
+func (r *Resources) Resolve(obj metav1.Object) []Edge {
+	resolversMu.RLock()
+	registered := append([]namedResolver(nil), resolvers...)
+	resolversMu.RUnlock()
+
+	var edges []Edge
+	seen := make(map[string]int)
+	for _, nr := range registered {
+		for _, edge := range nr.resolver.Resolve(obj, r) {
+			key := edgeKey(edge)
+			if i, ok := seen[key]; ok {
+				if edges[i].Type == EdgeGuess && edge.Type != EdgeGuess {
+					edges[i] = edge
+				}
+				continue
+			}
+			seen[key] = len(edges)
+			edges = append(edges, edge)
+		}
+	}
+	return edges
+}
+
+// edgeKey identifies the object an edge points at by its UID or, for objects
+// without one, by API group, kind and name, so that same-named kinds from
+// different groups are kept apart
+func edgeKey(edge Edge) string {
+	if uid := edge.Object.GetUID(); uid != "" {
+		return string(uid)
+	}
+	group := ""
+	if gk, ok := groupKindOf(edge.Object); ok {
+		group = gk.Group
+	}
+	return group + "/" + edge.Kind + "/" + edge.Object.GetName()
+}
+
+func init() {
+	RegisterResolver("services", ResolverFunc(resolveServices))
+	RegisterResolver("pod-template", ResolverFunc(resolvePodTemplate))
+}
+
+// resolveServices matches Service selectors against a workload's pod template
+// labels and the labels of its live pods. A StatefulSet also names its governing
+// Service; failing a match, a Service named after the StatefulSet is a guess.
+func resolveServices(obj metav1.Object, resources *Resources) []Edge {
+	podTemplate := PodTemplateOf(obj)
+	if podTemplate == nil {
+		return nil
+	}
+
+	selected := make(map[string]*corev1.Service)
+	for _, svc := range resources.ServicesSelecting(podTemplate.Labels) {
+		selected[svc.Name] = svc
+	}
+	for _, pod := range resources.GetWorkloadPods(obj) {
+		for _, svc := range resources.ServicesSelecting(pod.Labels) {
+			selected[svc.Name] = svc
+		}
+	}
+
+	names := make([]string, 0, len(selected))
+	for name := range selected {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var edges []Edge
+	for _, name := range names {
+		edges = append(edges, Edge{Type: EdgeSelects, Kind: "Service", Object: selected[name]})
+	}
+
+	if sts, ok := obj.(*appsv1.StatefulSet); ok {
+		if svc := resources.GetService(sts.Spec.ServiceName); svc != nil && selected[svc.Name] == nil {
+			selected[svc.Name] = svc
+			edges = append(edges, Edge{Type: EdgeGoverns, Kind: "Service", Object: svc})
+		}
+		for _, name := range []string{sts.Name, sts.Name + "-headless"} {
+			if svc := resources.GetService(name); svc != nil && selected[name] == nil {
+				edges = append(edges, Edge{Type: EdgeGuess, Kind: "Service", Object: svc})
+			}
+		}
+	}
+
+	return edges
+}
+
+// resolvePodTemplate finds the ConfigMaps, Secrets and PVCs a workload's pod
+// template uses through volumes and the environment of its containers
+func resolvePodTemplate(obj metav1.Object, resources *Resources) []Edge {
+	podTemplate := PodTemplateOf(obj)
+	if podTemplate == nil {
+		return nil
+	}
+	refs := newReferences()
+	refs.addPodSpec(&podTemplate.Spec)
+
+	var edges []Edge
+	for _, name := range sortedKeys(refs.configMaps) {
+		if cm := resources.GetConfigMap(name); cm != nil {
+			edges = append(edges, Edge{Type: refs.configMaps[name], Kind: "ConfigMap", Object: cm})
+		}
+	}
+	for _, name := range sortedKeys(refs.secrets) {
+		if secret := resources.GetSecret(name); secret != nil {
+			edges = append(edges, Edge{Type: refs.secrets[name], Kind: "Secret", Object: secret})
+		}
+	}
+	for _, name := range sortedKeys(refs.pvcs) {
+		if pvc := resources.GetPVC(name); pvc != nil {
+			edges = append(edges, Edge{Type: refs.pvcs[name], Kind: "PersistentVolumeClaim", Object: pvc})
+		}
+	}
+	return edges
+}
+
+// sortedKeys returns a map's keys in order, so edges come out deterministically
+func sortedKeys(m map[string]EdgeType) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
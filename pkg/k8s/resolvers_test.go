@@ -0,0 +1,119 @@
+package k8s
+
+import (
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestEdgeKey(t *testing.T) {
+	certificate := func(apiVersion, uid string) Edge {
+		obj := manifestObject(apiVersion, "Certificate", "web", nil)
+		obj.SetUID(types.UID(uid))
+		return Edge{Type: EdgeRefers, Kind: "Certificate", Object: obj}
+	}
+	secret := func(uid string) Edge {
+		return Edge{Type: EdgeMounts, Kind: "Secret", Object: &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "web", UID: types.UID(uid)}}}
+	}
+
+	tests := []struct {
+		name string
+		a, b Edge
+		same bool
+	}{
+		{name: "same object by UID", a: secret("1"), b: secret("1"), same: true},
+		{name: "recreated object", a: secret("1"), b: secret("2"), same: false},
+		{name: "same kind and group without UID", a: certificate("cert-manager.io/v1", ""), b: certificate("cert-manager.io/v1", ""), same: true},
+		{name: "other version of the group", a: certificate("cert-manager.io/v1", ""), b: certificate("cert-manager.io/v1alpha2", ""), same: true},
+		{name: "same kind in another group", a: certificate("cert-manager.io/v1", ""), b: certificate("networking.gke.io/v1", ""), same: false},
+		{name: "core group", a: secret(""), b: Edge{Kind: "Secret", Object: manifestObject("v1", "Secret", "web", nil)}, same: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := edgeKey(tt.a) == edgeKey(tt.b); got != tt.same {
+				t.Errorf("edgeKey(%q) == edgeKey(%q) is %v, want %v", edgeKey(tt.a), edgeKey(tt.b), got, tt.same)
+			}
+		})
+	}
+}
+
+func TestResolveKeepsKindsFromDifferentGroups(t *testing.T) {
+	resources, err := ManifestResources(nil, "ns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificates := []*unstructured.Unstructured{
+		manifestObject("cert-manager.io/v1", "Certificate", "web", nil),
+		manifestObject("networking.gke.io/v1", "Certificate", "web", nil),
+	}
+	RegisterResolver("test-certificates", ResolverFunc(func(obj metav1.Object, resources *Resources) []Edge {
+		if obj.GetName() != "test-certificates" {
+			return nil
+		}
+		var edges []Edge
+		for _, cert := range certificates {
+			edges = append(edges, Edge{Type: EdgeRefers, Kind: "Certificate", Object: cert})
+		}
+		return edges
+	}))
+
+	edges := resources.Resolve(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test-certificates"}})
+	if len(edges) != 2 {
+		t.Errorf("Resolve() = %d edges, want one per Certificate kind", len(edges))
+	}
+}
+
+func TestResolvePodTemplate(t *testing.T) {
+	var objects []*unstructured.Unstructured
+	for _, name := range []string{"env-from", "key-ref", "projected", "unused"} {
+		objects = append(objects, manifestObject("v1", "ConfigMap", name, nil))
+	}
+	for _, name := range []string{"init-env", "mounted-and-env", "pull"} {
+		objects = append(objects, manifestObject("v1", "Secret", name, nil))
+	}
+	resources, err := ManifestResources(objects, "ns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref := func(name string) corev1.LocalObjectReference { return corev1.LocalObjectReference{Name: name} }
+
+	dep := &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+		ImagePullSecrets: []corev1.LocalObjectReference{ref("pull")},
+		Volumes: []corev1.Volume{
+			{Name: "projected", VolumeSource: corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{Sources: []corev1.VolumeProjection{
+				{ConfigMap: &corev1.ConfigMapProjection{LocalObjectReference: ref("projected")}},
+			}}}},
+			{Name: "secret", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "mounted-and-env"}}},
+		},
+		InitContainers: []corev1.Container{{Name: "init", EnvFrom: []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: ref("init-env")}}}}},
+		Containers: []corev1.Container{{
+			Name:    "app",
+			EnvFrom: []corev1.EnvFromSource{{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: ref("env-from")}}},
+			Env: []corev1.EnvVar{
+				{Name: "LEVEL", ValueFrom: &corev1.EnvVarSource{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{LocalObjectReference: ref("key-ref"), Key: "level"}}},
+				{Name: "TOKEN", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: ref("mounted-and-env"), Key: "token"}}},
+			},
+		}},
+	}}}}
+
+	var got []string
+	for _, edge := range resolvePodTemplate(dep, resources) {
+		got = append(got, string(edge.Type)+" "+edge.Kind+"/"+edge.Object.GetName())
+	}
+	want := []string{
+		"env ConfigMap/env-from",
+		"env ConfigMap/key-ref",
+		"mounts ConfigMap/projected",
+		"env Secret/init-env",
+		"mounts Secret/mounted-and-env",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("resolvePodTemplate() = %q, want %q", got, want)
+	}
+}
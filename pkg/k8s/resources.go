@@ -1,223 +1,191 @@
 package k8s
 
 import (
-	"fmt"
+	"strconv"
 	"strings"
 
 	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	storagev1 "k8s.io/api/storage/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-// GetPodsByOwner returns all pods owned by the specified owner
-func (r *Resources) GetPodsByOwner(ownerKind, ownerName string) []*corev1.Pod {
-	var pods []*corev1.Pod
-	for i, pod := range r.Pods.Items {
-		for _, owner := range pod.OwnerReferences {
-			if owner.Kind == ownerKind && owner.Name == ownerName {
-				pods = append(pods, &r.Pods.Items[i])
-				break
-			}
-		}
-	}
-	return pods
+// revisionAnnotation is set by the deployment controller on each ReplicaSet
+const revisionAnnotation = "deployment.kubernetes.io/revision"
+
+// GetPodsByOwner returns all pods controlled by the specified owner. Ownership
+// is matched by UID, so pods left over from a deleted object of the same name
+// are not attributed to its replacement.
+func (r *Resources) GetPodsByOwner(owner metav1.Object) []*corev1.Pod {
+	return r.index().podsByOwner[owner.GetUID()]
 }
 
-// GetReplicaSetsByOwner returns all ReplicaSets owned by the specified owner
-func (r *Resources) GetReplicaSetsByOwner(ownerKind, ownerName string) []*appsv1.ReplicaSet {
-	var replicaSets []*appsv1.ReplicaSet
-	for i, rs := range r.ReplicaSets.Items {
-		for _, owner := range rs.OwnerReferences {
-			if owner.Kind == ownerKind && owner.Name == ownerName {
-				replicaSets = append(replicaSets, &r.ReplicaSets.Items[i])
-				break
-			}
-		}
-	}
-	return replicaSets
+// GetReplicaSetsByOwner returns all ReplicaSets controlled by the specified owner
+func (r *Resources) GetReplicaSetsByOwner(owner metav1.Object) []*appsv1.ReplicaSet {
+	return r.index().replicaSetsByOwner[owner.GetUID()]
 }
 
-// GetJobsByOwner returns all Jobs owned by the specified owner
-func (r *Resources) GetJobsByOwner(ownerKind, ownerName string) []*batchv1.Job {
-	var jobs []*batchv1.Job
-	for i, job := range r.Jobs.Items {
-		for _, owner := range job.OwnerReferences {
-			if owner.Kind == ownerKind && owner.Name == ownerName {
-				jobs = append(jobs, &r.Jobs.Items[i])
-				break
-			}
-		}
-	}
-	return jobs
-}
-
-// FindRelatedResources finds all resources related to a workload
-func (r *Resources) FindRelatedResources(workload metav1.Object, podSpec *corev1.PodSpec, found map[string]bool, debug bool) ([]*corev1.Service, []*corev1.ConfigMap, []*corev1.Secret, []*corev1.PersistentVolumeClaim) {
-	// Use maps to deduplicate resources
-	serviceMap := make(map[string]*corev1.Service)
-	configMapMap := make(map[string]*corev1.ConfigMap)
-	secretMap := make(map[string]*corev1.Secret)
-	pvcMap := make(map[string]*corev1.PersistentVolumeClaim)
-
-	workloadLabels := workload.GetLabels()
-	workloadName := workload.GetName()
-	
-	// Get workload kind and check for StatefulSet VolumeClaimTemplates
-	var workloadKind string
-	switch w := workload.(type) {
-	case *appsv1.StatefulSet:
-		workloadKind = "StatefulSet"
-		// Check VolumeClaimTemplates for StatefulSets
-		for _, template := range w.Spec.VolumeClaimTemplates {
-			pvcName := template.Name + "-" + workloadName + "-0"
-			for i, pvc := range r.PVCs.Items {
-				if pvc.Name == pvcName {
-					pvcMap[pvc.Name] = &r.PVCs.Items[i]
-				}
-			}
-		}
-	case *appsv1.Deployment:
-		workloadKind = "Deployment"
-	case *appsv1.DaemonSet:
-		workloadKind = "DaemonSet"
-	case *batchv1.Job:
-		workloadKind = "Job"
-	case *batchv1.CronJob:
-		workloadKind = "CronJob"
-	default:
-		workloadKind = "Unknown"
+// GetJobsByOwner returns all Jobs controlled by the specified owner
+func (r *Resources) GetJobsByOwner(owner metav1.Object) []*batchv1.Job {
+	return r.index().jobsByOwner[owner.GetUID()]
+}
+
+// GetControllerRevisionsByOwner returns all ControllerRevisions controlled by the specified owner
+func (r *Resources) GetControllerRevisionsByOwner(owner metav1.Object) []*appsv1.ControllerRevision {
+	return r.index().revisionsByOwner[owner.GetUID()]
+}
+
+// ReplicaSetRevision returns the deployment revision of a ReplicaSet, or 0 if unknown
+func ReplicaSetRevision(rs *appsv1.ReplicaSet) int64 {
+	revision, err := strconv.ParseInt(rs.Annotations[revisionAnnotation], 10, 64)
+	if err != nil {
+		return 0
 	}
+	return revision
+}
 
-	// Find related Services - don't use found map for services since they can be shared
-	for i, svc := range r.Services.Items {
-		if svc.Spec.Selector == nil {
-			continue
-		}
+// GetEndpointSlicesForService returns the EndpointSlices that belong to the named Service
+func (r *Resources) GetEndpointSlicesForService(serviceName string) []*discoveryv1.EndpointSlice {
+	return r.index().endpointSlicesByService[serviceName]
+}
 
-		matches := false
-		// Check if service selector matches workload labels
-		if len(svc.Spec.Selector) > 0 {
-			matches = true
-			for key, value := range svc.Spec.Selector {
-				if workloadLabels[key] != value {
-					matches = false
-					break
-				}
-			}
-		}
+// GetService returns the Service with the given name, or nil
+func (r *Resources) GetService(name string) *corev1.Service {
+	return r.index().services[name]
+}
 
-		// For StatefulSets, also check if service name matches workload name
-		if !matches && workloadKind == "StatefulSet" {
-			if svc.Name == workloadName || 
-			   svc.Name == workloadName+"-headless" {
-				matches = true
-			}
-		}
+// GetConfigMap returns the ConfigMap with the given name, or nil
+func (r *Resources) GetConfigMap(name string) *corev1.ConfigMap {
+	return r.index().configMaps[name]
+}
 
-		if matches {
-			serviceMap[svc.Name] = &r.Services.Items[i]
-		}
-	}
+// GetSecret returns the Secret with the given name, or nil
+func (r *Resources) GetSecret(name string) *corev1.Secret {
+	return r.index().secrets[name]
+}
 
-	// Find related resources from volumes and environment
-	if podSpec != nil {
-
-		// Check volumes
-		for _, vol := range podSpec.Volumes {
-			if vol.ConfigMap != nil {
-				for i, cm := range r.ConfigMaps.Items {
-					if cm.Name == vol.ConfigMap.Name {
-						configMapMap[cm.Name] = &r.ConfigMaps.Items[i]
-						break
-					}
-				}
-			}
+// GetPVC returns the PersistentVolumeClaim with the given name, or nil
+func (r *Resources) GetPVC(name string) *corev1.PersistentVolumeClaim {
+	return r.index().pvcs[name]
+}
 
-			if vol.Secret != nil {
-				for i, secret := range r.Secrets.Items {
-					if secret.Name == vol.Secret.SecretName {
-						secretMap[secret.Name] = &r.Secrets.Items[i]
-						break
-					}
-				}
-			}
+// GetNode returns the Node with the given name, or nil
+func (r *Resources) GetNode(name string) *corev1.Node {
+	return r.index().nodes[name]
+}
 
-			if vol.PersistentVolumeClaim != nil {
-				pvcFound := false
-				for i, pvc := range r.PVCs.Items {
-					// Direct name match
-					if pvc.Name == vol.PersistentVolumeClaim.ClaimName {
-						pvcMap[pvc.Name] = &r.PVCs.Items[i]
-						pvcFound = true
-						break
-					}
-				}
-
-				// For StatefulSets, also check for PVCs that match the pattern
-				if !pvcFound && workloadKind == "StatefulSet" {
-					for i, pvc := range r.PVCs.Items {
-						// Check for common StatefulSet PVC patterns
-						if strings.Contains(pvc.Name, workloadName) {
-							if debug {
-								fmt.Printf("Debug: Found StatefulSet PVC: %s for %s\n", 
-									pvc.Name, workloadName)
-							}
-							pvcMap[pvc.Name] = &r.PVCs.Items[i]
-						}
-					}
-				}
-			}
-		}
+// GetPersistentVolume returns the PersistentVolume with the given name, or nil
+func (r *Resources) GetPersistentVolume(name string) *corev1.PersistentVolume {
+	return r.index().persistentVolumes[name]
+}
+
+// GetStorageClass returns the StorageClass with the given name, or nil
+func (r *Resources) GetStorageClass(name string) *storagev1.StorageClass {
+	return r.index().storageClasses[name]
+}
+
+// GetVolumeAttachments returns the VolumeAttachments for the given PersistentVolume
+func (r *Resources) GetVolumeAttachments(pvName string) []*storagev1.VolumeAttachment {
+	return r.index().volumeAttachmentsByPV[pvName]
+}
+
+// GetStatefulSetPVCs returns the PVCs created from a StatefulSet's VolumeClaimTemplates.
+// PVCs for current ordinals are keyed by ordinal; PVCs left behind by ordinals that
+// were scaled away are returned separately.
+func (r *Resources) GetStatefulSetPVCs(sts *appsv1.StatefulSet) (map[int][]*corev1.PersistentVolumeClaim, []*corev1.PersistentVolumeClaim) {
+	byOrdinal := make(map[int][]*corev1.PersistentVolumeClaim)
+	var scaledDown []*corev1.PersistentVolumeClaim
+
+	start := 0
+	if sts.Spec.Ordinals != nil {
+		start = int(sts.Spec.Ordinals.Start)
+	}
+	replicas := 1
+	if sts.Spec.Replicas != nil {
+		replicas = int(*sts.Spec.Replicas)
+	}
 
-		// Check environment variables
-		for _, container := range podSpec.Containers {
-			// Check envFrom
-			for _, envFrom := range container.EnvFrom {
-				if envFrom.SecretRef != nil {
-					for i, secret := range r.Secrets.Items {
-						if secret.Name == envFrom.SecretRef.Name {
-							secretMap[secret.Name] = &r.Secrets.Items[i]
-							break
-						}
-					}
-				}
+	for _, template := range sts.Spec.VolumeClaimTemplates {
+		// PVCs are named <template>-<statefulset>-<ordinal>
+		prefix := template.Name + "-" + sts.Name + "-"
+		for _, pvc := range r.index().pvcsByPrefix[prefix] {
+			ordinal, ok := parseOrdinal(pvc.Name, prefix)
+			if !ok {
+				continue
 			}
-			
-			// Check individual env variables
-			for _, env := range container.Env {
-				if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil {
-					for i, secret := range r.Secrets.Items {
-						if secret.Name == env.ValueFrom.SecretKeyRef.Name {
-							secretMap[secret.Name] = &r.Secrets.Items[i]
-							break
-						}
-					}
-				}
+			if ordinal >= start && ordinal < start+replicas {
+				byOrdinal[ordinal] = append(byOrdinal[ordinal], pvc)
+			} else {
+				scaledDown = append(scaledDown, pvc)
 			}
 		}
 	}
 
-	// At the end, convert maps back to slices
-	services := make([]*corev1.Service, 0, len(serviceMap))
-	for _, svc := range serviceMap {
-		services = append(services, svc)
-	}
+	return byOrdinal, scaledDown
+}
 
-	configMaps := make([]*corev1.ConfigMap, 0, len(configMapMap))
-	for _, cm := range configMapMap {
-		configMaps = append(configMaps, cm)
-	}
+// StatefulSetPodOrdinal returns the ordinal of a pod created by the given StatefulSet
+func StatefulSetPodOrdinal(sts *appsv1.StatefulSet, pod *corev1.Pod) (int, bool) {
+	return parseOrdinal(pod.Name, sts.Name+"-")
+}
 
-	secrets := make([]*corev1.Secret, 0, len(secretMap))
-	for _, secret := range secretMap {
-		secrets = append(secrets, secret)
+// parseOrdinal returns the ordinal from a name of the form <prefix><ordinal>
+func parseOrdinal(name, prefix string) (int, bool) {
+	if !strings.HasPrefix(name, prefix) {
+		return 0, false
 	}
+	suffix := strings.TrimPrefix(name, prefix)
+	ordinal, err := strconv.Atoi(suffix)
+	if err != nil || ordinal < 0 || strconv.Itoa(ordinal) != suffix {
+		return 0, false
+	}
+	return ordinal, true
+}
 
-	pvcs := make([]*corev1.PersistentVolumeClaim, 0, len(pvcMap))
-	for _, pvc := range pvcMap {
-		pvcs = append(pvcs, pvc)
+// GetWorkloadPods returns the live pods of a workload, following ReplicaSets
+// for Deployments and Jobs for CronJobs
+func (r *Resources) GetWorkloadPods(workload metav1.Object) []*corev1.Pod {
+	switch w := workload.(type) {
+	case *appsv1.Deployment:
+		var pods []*corev1.Pod
+		for _, rs := range r.GetReplicaSetsByOwner(w) {
+			pods = append(pods, r.GetPodsByOwner(rs)...)
+		}
+		return pods
+	case *batchv1.CronJob:
+		var pods []*corev1.Pod
+		for _, job := range r.GetJobsByOwner(w) {
+			pods = append(pods, r.GetPodsByOwner(job)...)
+		}
+		return pods
+	case *appsv1.StatefulSet, *appsv1.DaemonSet, *batchv1.Job, *corev1.ReplicationController:
+		return r.GetPodsByOwner(workload)
+	default:
+		return nil
 	}
+}
 
-	return services, configMaps, secrets, pvcs
-}
\ No newline at end of file
+// PodTemplateOf returns the pod template of a built-in workload, or nil for
+// anything else
+func PodTemplateOf(obj metav1.Object) *corev1.PodTemplateSpec {
+	switch w := obj.(type) {
+	case *appsv1.Deployment:
+		return &w.Spec.Template
+	case *appsv1.StatefulSet:
+		return &w.Spec.Template
+	case *appsv1.DaemonSet:
+		return &w.Spec.Template
+	case *appsv1.ReplicaSet:
+		return &w.Spec.Template
+	case *batchv1.Job:
+		return &w.Spec.Template
+	case *batchv1.CronJob:
+		return &w.Spec.JobTemplate.Spec.Template
+	case *corev1.ReplicationController:
+		return w.Spec.Template
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,282 @@
+package k8s
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/yaml"
+)
+
+// EdgeRefers marks an object referenced by name from a field named in a rule
+const EdgeRefers EdgeType = "refers"
+
+// Rule matching modes
+const (
+	MatchName     = "name"     // The field holds a name, or a list of names
+	MatchSelector = "selector" // The field holds labels the related objects must carry
+)
+
+// Rule declares that a field of one kind references objects of another kind.
+// Kinds are written group/Kind, or just Kind for the core group, e.g.
+// kafka.strimzi.io/KafkaUser or Secret.
+type Rule struct {
+	From  string `json:"from"`
+	Path  string `json:"path"` // Dotted field path; "[]" after a list field visits every element
+	To    string `json:"to"`
+	Match string `json:"match,omitempty"` // "name" (default) or "selector"
+
+	from schema.GroupKind
+	to   schema.GroupKind
+	path []pathSegment
+}
+
+// pathSegment is one field of a rule's path
+type pathSegment struct {
+	field string
+	each  bool // Visit every element of a list field
+}
+
+// RuleSet holds the relationship rules from a config file
+type RuleSet struct {
+	Rules []Rule `json:"rules"`
+}
+
+// configFile is the layout of the config file. Sections other than rules
+// are read by the features they configure.
+type configFile struct {
+	RuleSet
+	Checks json.RawMessage `json:"checks,omitempty"`
+}
+
+// LoadRules reads relationship rules from a YAML file. A missing file is not
+// an error and yields no rules.
+func LoadRules(path string) (*RuleSet, error) {
+	rules := &RuleSet{}
+	if path == "" {
+		return rules, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return rules, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading rules: %v", err)
+	}
+
+	var file configFile
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return nil, fmt.Errorf("error parsing rules in %s: %v", path, err)
+	}
+	rules = &file.RuleSet
+
+	for i := range rules.Rules {
+		if err := rules.Rules[i].parse(); err != nil {
+			return nil, fmt.Errorf("rule %d in %s: %v", i+1, path, err)
+		}
+	}
+	return rules, nil
+}
+
+// parse validates a rule and splits its kinds and path
+func (rule *Rule) parse() error {
+	if rule.From == "" || rule.To == "" || rule.Path == "" {
+		return fmt.Errorf("from, path and to are required")
+	}
+	var err error
+	if rule.from, err = parseGroupKind(rule.From); err != nil {
+		return fmt.Errorf("from: %v", err)
+	}
+	if rule.to, err = parseGroupKind(rule.To); err != nil {
+		return fmt.Errorf("to: %v", err)
+	}
+
+	switch rule.Match {
+	case "":
+		rule.Match = MatchName
+	case MatchName, MatchSelector:
+	default:
+		return fmt.Errorf("match must be %q or %q, not %q", MatchName, MatchSelector, rule.Match)
+	}
+
+	for _, field := range strings.Split(rule.Path, ".") {
+		segment := pathSegment{field: strings.TrimSuffix(field, "[]")}
+		segment.each = segment.field != field
+		if segment.field == "" {
+			return fmt.Errorf("invalid path %q", rule.Path)
+		}
+		rule.path = append(rule.path, segment)
+	}
+	return nil
+}
+
+// parseGroupKind splits group/Kind; a bare Kind is in the core group. A
+// version, as in group/version/Kind, is rejected rather than read as part
+// of the group.
+func parseGroupKind(s string) (schema.GroupKind, error) {
+	parts := strings.Split(s, "/")
+	switch {
+	case len(parts) == 1 && parts[0] != "":
+		return schema.GroupKind{Kind: parts[0]}, nil
+	case len(parts) == 2 && parts[0] != "" && parts[1] != "":
+		return schema.GroupKind{Group: parts[0], Kind: parts[1]}, nil
+	default:
+		return schema.GroupKind{}, fmt.Errorf("kind %q must be written group/Kind, or Kind for the core group", s)
+	}
+}
+
+// CustomKinds returns the kinds named by the rules that GetResources does not fetch
+func (rs *RuleSet) CustomKinds() []schema.GroupKind {
+	var kinds []schema.GroupKind
+	seen := make(map[schema.GroupKind]bool)
+	for _, rule := range rs.Rules {
+		for _, gk := range []schema.GroupKind{rule.from, rule.to} {
+			if !seen[gk] && !IsBuiltinKind(gk) {
+				seen[gk] = true
+				kinds = append(kinds, gk)
+			}
+		}
+	}
+	return kinds
+}
+
+// SourceKinds returns the custom kinds that rules draw edges from, in rule order
+func (rs *RuleSet) SourceKinds() []schema.GroupKind {
+	var kinds []schema.GroupKind
+	seen := make(map[schema.GroupKind]bool)
+	for _, rule := range rs.Rules {
+		if !seen[rule.from] && !IsBuiltinKind(rule.from) {
+			seen[rule.from] = true
+			kinds = append(kinds, rule.from)
+		}
+	}
+	return kinds
+}
+
+// Resolve applies every rule whose source kind matches the object
+func (rs *RuleSet) Resolve(obj metav1.Object, resources *Resources) []Edge {
+	gk, ok := groupKindOf(obj)
+	if !ok {
+		return nil
+	}
+
+	var content map[string]interface{}
+	var edges []Edge
+	for i := range rs.Rules {
+		rule := &rs.Rules[i]
+		if rule.from != gk {
+			continue
+		}
+		if content == nil {
+			var err error
+			if content, err = toUnstructured(obj); err != nil {
+				return nil
+			}
+		}
+
+		for _, value := range extractPath(content, rule.path) {
+			edges = append(edges, rule.resolveValue(value, resources)...)
+		}
+	}
+	return edges
+}
+
+// resolveValue turns one value found at a rule's path into edges
+func (rule *Rule) resolveValue(value interface{}, resources *Resources) []Edge {
+	var edges []Edge
+	switch rule.Match {
+	case MatchSelector:
+		selector, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		set := make(map[string]string, len(selector))
+		for key, v := range selector {
+			if s, ok := v.(string); ok {
+				set[key] = s
+			}
+		}
+		for _, obj := range resources.ObjectsMatchingSelector(rule.to, set) {
+			edges = append(edges, Edge{Type: EdgeSelects, Kind: rule.to.Kind, Object: obj})
+		}
+	default:
+		var names []string
+		switch v := value.(type) {
+		case string:
+			names = append(names, v)
+		case []interface{}:
+			for _, item := range v {
+				if s, ok := item.(string); ok {
+					names = append(names, s)
+				}
+			}
+		}
+		for _, name := range names {
+			if obj := resources.GetObject(rule.to, name); obj != nil {
+				edges = append(edges, Edge{Type: EdgeRefers, Kind: rule.to.Kind, Object: obj})
+			}
+		}
+	}
+	return edges
+}
+
+// extractPath returns the values at a path, visiting every element of lists
+// marked with "[]". Missing fields yield nothing.
+func extractPath(value interface{}, path []pathSegment) []interface{} {
+	if len(path) == 0 {
+		return []interface{}{value}
+	}
+
+	fields, ok := value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	next, ok := fields[path[0].field]
+	if !ok {
+		return nil
+	}
+	if !path[0].each {
+		return extractPath(next, path[1:])
+	}
+
+	items, ok := next.([]interface{})
+	if !ok {
+		return nil
+	}
+	var values []interface{}
+	for _, item := range items {
+		values = append(values, extractPath(item, path[1:])...)
+	}
+	return values
+}
+
+// groupKindOf returns the kind of a typed or unstructured object
+func groupKindOf(obj metav1.Object) (schema.GroupKind, bool) {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		return u.GroupVersionKind().GroupKind(), true
+	}
+	runtimeObj, ok := obj.(runtime.Object)
+	if !ok {
+		return schema.GroupKind{}, false
+	}
+	gvks, _, err := scheme.Scheme.ObjectKinds(runtimeObj)
+	if err != nil || len(gvks) == 0 {
+		return schema.GroupKind{}, false
+	}
+	return gvks[0].GroupKind(), true
+}
+
+// toUnstructured returns an object's fields as generic maps and lists
+func toUnstructured(obj metav1.Object) (map[string]interface{}, error) {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		return u.Object, nil
+	}
+	return runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+}
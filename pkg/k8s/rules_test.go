@@ -0,0 +1,220 @@
+package k8s
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestParseGroupKind(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    schema.GroupKind
+		wantErr bool
+	}{
+		{in: "Secret", want: schema.GroupKind{Kind: "Secret"}},
+		{in: "apps/Deployment", want: schema.GroupKind{Group: "apps", Kind: "Deployment"}},
+		{in: "kafka.strimzi.io/KafkaUser", want: schema.GroupKind{Group: "kafka.strimzi.io", Kind: "KafkaUser"}},
+		{in: "kafka.strimzi.io/v1beta2/KafkaUser", wantErr: true},
+		{in: "apps/", wantErr: true},
+		{in: "/Deployment", wantErr: true},
+		{in: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseGroupKind(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseGroupKind(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseGroupKind(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{
+			name:   "valid",
+			config: "rules:\n- from: kafka.strimzi.io/KafkaUser\n  path: spec.secretRef.name\n  to: Secret\n",
+		},
+		{
+			name:    "version in kind",
+			config:  "rules:\n- from: kafka.strimzi.io/v1beta2/KafkaUser\n  path: spec.secretRef.name\n  to: Secret\n",
+			wantErr: "from: kind",
+		},
+		{
+			name:    "missing path",
+			config:  "rules:\n- from: apps/Deployment\n  to: Secret\n",
+			wantErr: "required",
+		},
+		{
+			name:    "unknown match",
+			config:  "rules:\n- from: apps/Deployment\n  path: spec\n  to: Secret\n  match: regex\n",
+			wantErr: "match must be",
+		},
+		{
+			name:    "empty path segment",
+			config:  "rules:\n- from: apps/Deployment\n  path: spec..name\n  to: Secret\n",
+			wantErr: "invalid path",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.config), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := LoadRules(path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("LoadRules() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("LoadRules() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExtractPath(t *testing.T) {
+	content := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"secretName": "db",
+			"users": []interface{}{
+				map[string]interface{}{"secret": "alice"},
+				map[string]interface{}{"secret": "bob"},
+				map[string]interface{}{"name": "carol"},
+			},
+			"tags": []interface{}{"a", "b"},
+		},
+	}
+
+	tests := []struct {
+		path string
+		want []interface{}
+	}{
+		{path: "spec.secretName", want: []interface{}{"db"}},
+		{path: "spec.users[].secret", want: []interface{}{"alice", "bob"}},
+		{path: "spec.tags[]", want: []interface{}{"a", "b"}},
+		{path: "spec.tags", want: []interface{}{[]interface{}{"a", "b"}}},
+		{path: "spec.missing", want: nil},
+		{path: "spec.secretName.deeper", want: nil},
+		{path: "spec.secretName[]", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rule := Rule{From: "Widget", Path: tt.path, To: "Secret"}
+			if err := rule.parse(); err != nil {
+				t.Fatal(err)
+			}
+			got := extractPath(content, rule.path)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("extractPath(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuleSetResolve(t *testing.T) {
+	widgetKind := schema.GroupKind{Group: "example.com", Kind: "Widget"}
+	widget := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "Widget",
+		"metadata":   map[string]interface{}{"name": "w", "namespace": "ns"},
+		"spec": map[string]interface{}{
+			"secretRefs": []interface{}{
+				map[string]interface{}{"name": "db"},
+				map[string]interface{}{"name": "missing"},
+			},
+			"configMaps": []interface{}{"settings", "other"},
+			"selector":   map[string]interface{}{"app": "web"},
+		},
+	}}
+	resources, err := ManifestResources([]*unstructured.Unstructured{
+		manifestObject("v1", "Secret", "db", nil),
+		manifestObject("v1", "ConfigMap", "settings", nil),
+		manifestObject("v1", "ConfigMap", "other", nil),
+		manifestObject("v1", "Pod", "web-1", map[string]interface{}{"app": "web"}),
+		manifestObject("v1", "Pod", "api-1", map[string]interface{}{"app": "api"}),
+	}, "ns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resources.Custom = map[schema.GroupKind][]*unstructured.Unstructured{widgetKind: {widget}}
+
+	tests := []struct {
+		name  string
+		rules []Rule
+		want  []string
+	}{
+		{
+			name:  "name in list of objects",
+			rules: []Rule{{From: "example.com/Widget", Path: "spec.secretRefs[].name", To: "Secret"}},
+			want:  []string{"refers Secret/db"},
+		},
+		{
+			name:  "list of names",
+			rules: []Rule{{From: "example.com/Widget", Path: "spec.configMaps", To: "ConfigMap"}},
+			want:  []string{"refers ConfigMap/other", "refers ConfigMap/settings"},
+		},
+		{
+			name:  "selector",
+			rules: []Rule{{From: "example.com/Widget", Path: "spec.selector", To: "Pod", Match: MatchSelector}},
+			want:  []string{"selects Pod/web-1"},
+		},
+		{
+			name:  "other group",
+			rules: []Rule{{From: "other.example.com/Widget", Path: "spec.secretRefs[].name", To: "Secret"}},
+			want:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs := &RuleSet{Rules: tt.rules}
+			for i := range rs.Rules {
+				if err := rs.Rules[i].parse(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var got []string
+			for _, edge := range rs.Resolve(widget, resources) {
+				got = append(got, string(edge.Type)+" "+edge.Kind+"/"+edge.Object.GetName())
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Resolve() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// manifestObject returns a minimal object as it would be read from a manifest
+func manifestObject(apiVersion, kind, name string, labels map[string]interface{}) *unstructured.Unstructured {
+	metadata := map[string]interface{}{"name": name}
+	if labels != nil {
+		metadata["labels"] = labels
+	}
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       kind,
+		"metadata":   metadata,
+	}}
+}
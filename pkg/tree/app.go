@@ -0,0 +1,92 @@
+package tree
+
+import "sort"
+
+// Recommended labels, see https://kubernetes.io/docs/concepts/overview/working-with-objects/common-labels/
+const (
+	appPartOfLabel    = "app.kubernetes.io/part-of"
+	appNameLabel      = "app.kubernetes.io/name"
+	appComponentLabel = "app.kubernetes.io/component"
+	appInstanceLabel  = "app.kubernetes.io/instance"
+)
+
+// groupByApp builds an Application -> Component -> workload hierarchy from the
+// recommended labels. The application is the part-of label, or the name label
+// for a standalone application. Within an application made of parts, the
+// component label or else the part's name becomes the component.
+func (b *Builder) groupByApp(root *Resource) {
+	apps := make(map[string]*Resource)
+	regroup(root, apps, func(node *Resource) string {
+		meta := nodeMeta(node)
+		if meta == nil {
+			return ""
+		}
+		labels := meta.GetLabels()
+		if partOf := labels[appPartOfLabel]; partOf != "" {
+			return partOf
+		}
+		return labels[appNameLabel]
+	}, func(name string) *Resource {
+		return &Resource{
+			Kind:     "Application",
+			Name:     name,
+			Children: make([]*Resource, 0),
+		}
+	})
+
+	for _, app := range apps {
+		regroup(app, make(map[string]*Resource), func(node *Resource) string {
+			labels := nodeMeta(node).GetLabels()
+			if component := labels[appComponentLabel]; component != "" {
+				return component
+			}
+			if labels[appPartOfLabel] != "" && labels[appNameLabel] != app.Name {
+				return labels[appNameLabel]
+			}
+			return ""
+		}, func(name string) *Resource {
+			return &Resource{
+				Kind:     "Component",
+				Name:     name,
+				Children: make([]*Resource, 0),
+			}
+		})
+
+		addInstanceDetails(app)
+		for _, child := range app.Children {
+			if child.Kind == "Component" {
+				addInstanceDetails(child)
+			}
+		}
+	}
+}
+
+// addInstanceDetails labels a group with the instance labels of the workloads
+// under it, so separate installs of the same application can be told apart
+func addInstanceDetails(group *Resource) {
+	seen := make(map[string]bool)
+	var walk func(node *Resource)
+	walk = func(node *Resource) {
+		for _, child := range node.Children {
+			if child.Kind == "Component" {
+				walk(child)
+				continue
+			}
+			if meta := nodeMeta(child); meta != nil {
+				if instance := meta.GetLabels()[appInstanceLabel]; instance != "" {
+					seen[instance] = true
+				}
+			}
+		}
+	}
+	walk(group)
+
+	instances := make([]string, 0, len(seen))
+	for instance := range seen {
+		instances = append(instances, instance)
+	}
+	sort.Strings(instances)
+	for _, instance := range instances {
+		group.Details = append(group.Details, "instance="+instance)
+	}
+}
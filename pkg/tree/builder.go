@@ -2,19 +2,38 @@ package tree
 
 import (
 	"fmt"
+	"os"
+	"sort"
 	"kubectl-tree/pkg/k8s"
 
 	appsv1 "k8s.io/api/apps/v1"
-	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
 )
 
+// Options controls what the builder adds to the tree
+type Options struct {
+	Debug   bool // Print debug output while building
+	Wide    bool // Add image, resources, ports and probes to containers, and QoS, IP and node to pods
+	Metrics bool // Show live CPU and memory usage from the metrics API
+	Events  bool // Attach recent Warning events to the nodes they concern
+
+	FollowCrossNamespace bool // Follow references into other namespaces and show them as foreign nodes
+
+	Rules   *k8s.RuleSet // Relationship rules for custom resources, if any
+	GroupBy string       // Grouping layer above the workloads, one of GroupByModes, or empty for none
+}
+
 // Builder handles building the resource tree
 type Builder struct {
-	client    *k8s.Client
-	debug     bool
-	resources *k8s.Resources // Add this field
+	client     *k8s.Client
+	options    Options
+	resources  *k8s.Resources // Add this field
+	podMetrics map[string]*metricsv1beta1.PodMetrics
+	placed     map[types.UID]bool       // Pods, ReplicaSets and Jobs already added to the tree
+	foreign    map[string]metav1.Object // Objects fetched from other namespaces, nil if missing
 }
 
 // Update BuildTree to store resources
@@ -27,26 +46,36 @@ func (b *Builder) BuildTree(namespace string) (*Resource, error) {
 	
 	// Store resources for later use
 	b.resources = resources
+	b.placed = make(map[types.UID]bool)
+	b.foreign = make(map[string]metav1.Object)
+	b.loadCustomResources(namespace)
 	
 	// Check if namespace is empty
 	if len(resources.Deployments.Items) == 0 &&
 		len(resources.StatefulSets.Items) == 0 &&
 		len(resources.DaemonSets.Items) == 0 &&
 		len(resources.Jobs.Items) == 0 &&
-		len(resources.CronJobs.Items) == 0 {
-		fmt.Printf("No resources found in %s namespace.\n", namespace)
-		return nil, nil
+		len(resources.CronJobs.Items) == 0 &&
+		len(resources.ReplicationControllers.Items) == 0 &&
+		len(resources.Pods.Items) == 0 &&
+		len(resources.Custom) == 0 {
+		return nil, &EmptyError{Namespace: namespace, What: "resources"}
 	}
 
+	return b.buildTree(namespace), nil
+}
+
+// buildTree builds the tree from the resources already loaded into the builder
+func (b *Builder) buildTree(namespace string) *Resource {
+	resources := b.resources
+	b.loadMetrics(namespace)
+
 	root := &Resource{
 		Kind:     "Namespace",
 		Name:     namespace,
 		Children: make([]*Resource, 0),
 	}
 
-	// Create shared found map
-	found := make(map[string]bool)
-
 	// Add Deployments
 	for i := range resources.Deployments.Items {
 		dep := &resources.Deployments.Items[i]
@@ -54,54 +83,32 @@ func (b *Builder) BuildTree(namespace string) (*Resource, error) {
 		depNode := &Resource{
 			Kind:     "Deployment",
 			Name:     dep.Name,
+			Object:   dep,
 			Children: make([]*Resource, 0),
 		}
 		root.Children = append(root.Children, depNode)
 
 		// Add related resources
-		b.addRelatedResources(dep, depNode, resources, found)
+		b.addRelatedResources(dep, depNode)
 
-		// Add ReplicaSets
-		for _, rs := range resources.GetReplicaSetsByOwner("Deployment", dep.Name) {
+		// Add ReplicaSets newest revision first, labelled with their rollout history
+		replicaSets := resources.GetReplicaSetsByOwner(dep)
+		sortReplicaSetsByRevision(replicaSets)
+		for i, rs := range replicaSets {
 			rsNode := &Resource{
 				Kind:     "ReplicaSet",
 				Name:     rs.Name,
+				Object:   rs,
+				Details:  replicaSetHistoryDetails(replicaSets, i),
 				Children: make([]*Resource, 0),
 			}
 			depNode.Children = append(depNode.Children, rsNode)
+			b.placed[rs.UID] = true
 
 			// Add Pods
-			for _, pod := range resources.GetPodsByOwner("ReplicaSet", rs.Name) {
-			    podNode := &Resource{
-			        Kind:     "Pod",
-			        Name:     pod.Name,
-			        Children: make([]*Resource, 0),
-			    }
-			    rsNode.Children = append(rsNode.Children, podNode)
-			    
-			    // Add init containers to the pod first
-			    for _, initContainer := range pod.Spec.InitContainers {
-			        initContainerNode := &Resource{
-			            Kind:     "InitContainer",
-			            Name:     initContainer.Name,
-			            Children: make([]*Resource, 0),
-			        }
-			        podNode.Children = append(podNode.Children, initContainerNode)
-			    }
-			    
-			    // Add regular containers to the pod
-			    for _, container := range pod.Spec.Containers {
-			        containerNode := &Resource{
-			            Kind:     "Container",
-			            Name:     container.Name,
-			            Children: make([]*Resource, 0),
-			        }
-			        podNode.Children = append(podNode.Children, containerNode)
-			    }
+			for _, pod := range resources.GetPodsByOwner(rs) {
+				rsNode.Children = append(rsNode.Children, b.newPodNode(pod))
 			}
-			
-			// Remove the call to addContainersToTree at the end of BuildTree
-			// b.addContainersToTree(root)
 		}
 	}
 
@@ -111,42 +118,51 @@ func (b *Builder) BuildTree(namespace string) (*Resource, error) {
 		stsNode := &Resource{
 			Kind:     "StatefulSet",
 			Name:     sts.Name,
+			Object:   sts,
 			Children: make([]*Resource, 0),
 		}
 		root.Children = append(root.Children, stsNode)
 
 		// Add related resources first
-		b.addRelatedResources(sts, stsNode, resources, found)
-
-		// Add Pods last so they appear after the related resources
-		// For StatefulSets
-		for _, pod := range resources.GetPodsByOwner("StatefulSet", sts.Name) {
-		    podNode := &Resource{
-		        Kind:     "Pod",
-		        Name:     pod.Name,
-		        Children: make([]*Resource, 0),
-		    }
-		    stsNode.Children = append(stsNode.Children, podNode)
-		    
-		    // Add init containers to the pod first
-		    for _, initContainer := range pod.Spec.InitContainers {
-		        initContainerNode := &Resource{
-		            Kind:     "InitContainer",
-		            Name:     initContainer.Name,
-		            Children: make([]*Resource, 0),
-		        }
-		        podNode.Children = append(podNode.Children, initContainerNode)
-		    }
-		    
-		    // Add regular containers to the pod
-		    for _, container := range pod.Spec.Containers {
-		        containerNode := &Resource{
-		            Kind:     "Container",
-		            Name:     container.Name,
-		            Children: make([]*Resource, 0),
-		        }
-		        podNode.Children = append(podNode.Children, containerNode)
-		    }
+		b.addRelatedResources(sts, stsNode)
+
+		// Add revision history
+		b.addControllerRevisions(stsNode, sts, sts.Status.UpdateRevision)
+
+		// Add Pods last so they appear after the related resources,
+		// each with the PVCs created for its ordinal
+		pvcsByOrdinal, scaledDownPVCs := resources.GetStatefulSetPVCs(sts)
+		for _, pod := range resources.GetPodsByOwner(sts) {
+			podNode := b.newPodNode(pod)
+			if ordinal, ok := k8s.StatefulSetPodOrdinal(sts, pod); ok {
+				for _, pvc := range pvcsByOrdinal[ordinal] {
+					podNode.Children = append(podNode.Children, b.newPVCNode(pvc))
+				}
+				delete(pvcsByOrdinal, ordinal)
+			}
+			stsNode.Children = append(stsNode.Children, podNode)
+		}
+
+		// PVCs of current ordinals whose pod is gone
+		missingOrdinals := make([]int, 0, len(pvcsByOrdinal))
+		for ordinal := range pvcsByOrdinal {
+			missingOrdinals = append(missingOrdinals, ordinal)
+		}
+		sort.Ints(missingOrdinals)
+		for _, ordinal := range missingOrdinals {
+			for _, pvc := range pvcsByOrdinal[ordinal] {
+				stsNode.Children = append(stsNode.Children, b.newPVCNode(pvc, "pod missing"))
+			}
+		}
+
+		// PVCs left behind by scaled-down ordinals, labelled by what the
+		// retention policy will do with them
+		scaledDownDetail := "retained"
+		if policy := sts.Spec.PersistentVolumeClaimRetentionPolicy; policy != nil && policy.WhenScaled == appsv1.DeletePersistentVolumeClaimRetentionPolicyType {
+			scaledDownDetail = "scaled down, pending deletion"
+		}
+		for _, pvc := range scaledDownPVCs {
+			stsNode.Children = append(stsNode.Children, b.newPVCNode(pvc, scaledDownDetail))
 		}
 	}
 
@@ -156,87 +172,43 @@ func (b *Builder) BuildTree(namespace string) (*Resource, error) {
 		dsNode := &Resource{
 			Kind:     "DaemonSet",
 			Name:     ds.Name,
+			Object:   ds,
 			Children: make([]*Resource, 0),
 		}
 		root.Children = append(root.Children, dsNode)
 
 		// Add related resources
-		b.addRelatedResources(ds, dsNode, resources, found)
+		b.addRelatedResources(ds, dsNode)
+
+		// Add revision history
+		b.addControllerRevisions(dsNode, ds, "")
 
 		// Add Pods
-		// For DaemonSets
-		for _, pod := range resources.GetPodsByOwner("DaemonSet", ds.Name) {
-		    podNode := &Resource{
-		        Kind:     "Pod",
-		        Name:     pod.Name,
-		        Children: make([]*Resource, 0),
-		    }
-		    dsNode.Children = append(dsNode.Children, podNode)
-		    
-		    // Add init containers to the pod first
-		    for _, initContainer := range pod.Spec.InitContainers {
-		        initContainerNode := &Resource{
-		            Kind:     "InitContainer",
-		            Name:     initContainer.Name,
-		            Children: make([]*Resource, 0),
-		        }
-		        podNode.Children = append(podNode.Children, initContainerNode)
-		    }
-		    
-		    // Add regular containers to the pod
-		    for _, container := range pod.Spec.Containers {
-		        containerNode := &Resource{
-		            Kind:     "Container",
-		            Name:     container.Name,
-		            Children: make([]*Resource, 0),
-		        }
-		        podNode.Children = append(podNode.Children, containerNode)
-		    }
+		for _, pod := range resources.GetPodsByOwner(ds) {
+			dsNode.Children = append(dsNode.Children, b.newPodNode(pod))
 		}
 	}
 
-	// Add standalone Jobs (not owned by CronJobs)
+	// Add standalone Jobs; Jobs with a controller appear under their CronJob,
+	// or in the Other section if that owner is gone
 	for i := range resources.Jobs.Items {
 		job := &resources.Jobs.Items[i]
-		if len(job.OwnerReferences) == 0 || job.OwnerReferences[0].Kind != "CronJob" {
+		if metav1.GetControllerOf(job) == nil {
 			jobNode := &Resource{
 				Kind:     "Job",
 				Name:     job.Name,
+				Object:   job,
 				Children: make([]*Resource, 0),
 			}
 			root.Children = append(root.Children, jobNode)
+			b.placed[job.UID] = true
 
 			// Add related resources
-			b.addRelatedResources(job, jobNode, resources, found)
+			b.addRelatedResources(job, jobNode)
 
 			// Add Pods
-			for _, pod := range resources.GetPodsByOwner("Job", job.Name) {
-				podNode := &Resource{
-					Kind:     "Pod",
-					Name:     pod.Name,
-					Children: make([]*Resource, 0),
-				}
-				jobNode.Children = append(jobNode.Children, podNode)
-				
-				// Add containers to the pod
-				for _, initContainer := range pod.Spec.InitContainers {
-					initContainerNode := &Resource{
-						Kind:     "InitContainer",
-						Name:     initContainer.Name,
-						Children: make([]*Resource, 0),
-					}
-					podNode.Children = append(podNode.Children, initContainerNode)
-				}
-				
-				// Add regular containers to the pod
-				for _, container := range pod.Spec.Containers {
-					containerNode := &Resource{
-						Kind:     "Container",
-						Name:     container.Name,
-						Children: make([]*Resource, 0),
-					}
-					podNode.Children = append(podNode.Children, containerNode)
-				}
+			for _, pod := range resources.GetPodsByOwner(job) {
+				jobNode.Children = append(jobNode.Children, b.newPodNode(pod))
 			}
 		}
 	}
@@ -247,185 +219,151 @@ func (b *Builder) BuildTree(namespace string) (*Resource, error) {
 		cronJobNode := &Resource{
 			Kind:     "CronJob",
 			Name:     cronJob.Name,
+			Object:   cronJob,
 			Children: make([]*Resource, 0),
 		}
 		root.Children = append(root.Children, cronJobNode)
 
 		// Add Jobs owned by this CronJob
-		for _, job := range resources.GetJobsByOwner("CronJob", cronJob.Name) {
+		for _, job := range resources.GetJobsByOwner(cronJob) {
 			jobNode := &Resource{
 				Kind:     "Job",
 				Name:     job.Name,
+				Object:   job,
 				Children: make([]*Resource, 0),
 			}
 			cronJobNode.Children = append(cronJobNode.Children, jobNode)
+			b.placed[job.UID] = true
 
 			// Add Pods
-			for _, pod := range resources.GetPodsByOwner("Job", job.Name) {
-				podNode := &Resource{
-					Kind:     "Pod",
-					Name:     pod.Name,
-					Children: make([]*Resource, 0),
-				}
-				jobNode.Children = append(jobNode.Children, podNode)
-				
-				// Add containers to the pod
-				for _, initContainer := range pod.Spec.InitContainers {
-					initContainerNode := &Resource{
-						Kind:     "InitContainer",
-						Name:     initContainer.Name,
-						Children: make([]*Resource, 0),
-					}
-					podNode.Children = append(podNode.Children, initContainerNode)
-				}
-				
-				// Add regular containers to the pod
-				for _, container := range pod.Spec.Containers {
-					containerNode := &Resource{
-						Kind:     "Container",
-						Name:     container.Name,
-						Children: make([]*Resource, 0),
-					}
-					podNode.Children = append(podNode.Children, containerNode)
-				}
+			for _, pod := range resources.GetPodsByOwner(job) {
+				jobNode.Children = append(jobNode.Children, b.newPodNode(pod))
 			}
 		}
 	}
 
-	// Remove this line to prevent adding containers twice
-	// b.addContainersToTree(root)
-
-	return root, nil
-}
+	// Add custom resources with the objects their rules reference
+	b.addCustomResources(root)
 
-// New method to add containers to pods in the tree
-// Fix the addContainersToTree method to use Resource instead of node
-// Fix the addContainersToTree method to properly handle errors and use the correct parameters
-func (b *Builder) addContainersToTree(node *Resource) {
-    // Skip if node is nil
-    if node == nil {
-        return
-    }
-    
-    // Process Pod nodes
-    if node.Kind == "Pod" {
-        // Find the pod in the already fetched resources instead of making a new API call
-        for _, child := range node.Children {
-            // Skip if already processed
-            if child.Kind == "Container" {
-                continue
-            }
-        }
-        
-        // Just add containers from the pod spec directly
-        // This is safer than making additional API calls
-        for _, pod := range b.resources.Pods.Items {
-            if pod.Name == node.Name {
-                for _, container := range pod.Spec.Containers {
-                    containerNode := &Resource{
-                        Kind:     "Container",
-                        Name:     container.Name,
-                        Children: make([]*Resource, 0),
-                    }
-                    node.Children = append(node.Children, containerNode)
-                }
-                break
-            }
-        }
-    }
-    
-    // Process children recursively
-    for _, child := range node.Children {
-        b.addContainersToTree(child)
-    }
-}
+	// Add pods the workloads above did not reach
+	if otherNode := b.newOtherNode(); otherNode != nil {
+		root.Children = append(root.Children, otherNode)
+	}
 
-// addRelatedResources adds related resources as children of the workload node
-func (b *Builder) addRelatedResources(workload metav1.Object, workloadNode *Resource, resources *k8s.Resources, found map[string]bool) {
-	// Get the PodSpec from the workload
-	var podSpec *corev1.PodSpec
-	switch w := workload.(type) {
-	case *appsv1.StatefulSet:
-		podSpec = &w.Spec.Template.Spec
-	case *appsv1.Deployment:
-		podSpec = &w.Spec.Template.Spec
-	case *appsv1.DaemonSet:
-		podSpec = &w.Spec.Template.Spec
-	case *batchv1.Job:
-		podSpec = &w.Spec.Template.Spec
-	case *batchv1.CronJob:
-		podSpec = &w.Spec.JobTemplate.Spec.Template.Spec
-	default:
-		return // Early return if workload type is not supported
+	// Add references that leave the namespace
+	if crossNamespaceNode := b.newCrossNamespaceNode(namespace); crossNamespaceNode != nil {
+		root.Children = append(root.Children, crossNamespaceNode)
 	}
 
-	// Find related resources
-	services, configMaps, secrets, pvcs := resources.FindRelatedResources(workload, podSpec, found, b.debug)
+	b.groupTree(root)
+
+	b.addEvents(root, namespace)
 
-	if b.debug {
-		fmt.Printf("Debug: Found resources for %s: secrets=%d, pvcs=%d, configmaps=%d, services=%d\n",
-			workload.GetName(), len(secrets), len(pvcs), len(configMaps), len(services))
+	if b.podMetrics != nil {
+		rollupUsage(root)
 	}
 
-	// Add Services
-	for _, svc := range services {
-		if b.debug {
-			fmt.Printf("\tDebug: Adding Service %s to %s\n", svc.Name, workload.GetName())
-		}
-		svcNode := &Resource{
-			Kind: "Service",
-			Name: svc.Name,
+	return root
+}
 
-			Children: make([]*Resource, 0),
+// newPodNode creates a Pod node with its init containers followed by its containers
+func (b *Builder) newPodNode(pod *corev1.Pod) *Resource {
+	podNode := &Resource{
+		Kind:     "Pod",
+		Name:     pod.Name,
+		Object:   pod,
+		Children: make([]*Resource, 0),
+	}
+	if b.options.Wide {
+		podNode.Details = podDetails(pod)
+	}
+	b.placed[pod.UID] = true
+
+	// Native sidecars are init containers that keep running beside the app
+	// containers, so they are shown as their own kind with usage
+	for i := range pod.Spec.InitContainers {
+		initContainer := &pod.Spec.InitContainers[i]
+		if initContainer.RestartPolicy != nil && *initContainer.RestartPolicy == corev1.ContainerRestartPolicyAlways {
+			sidecarNode := b.newContainerNode("SidecarContainer", initContainer, initContainer, pod.Status.InitContainerStatuses)
+			b.addContainerUsage(sidecarNode, initContainer, pod)
+			podNode.Children = append(podNode.Children, sidecarNode)
+			continue
 		}
-		workloadNode.Children = append(workloadNode.Children, svcNode)
+		podNode.Children = append(podNode.Children, b.newContainerNode("InitContainer", initContainer, initContainer, pod.Status.InitContainerStatuses))
 	}
 
-	// Add ConfigMaps
-	for _, cm := range configMaps {
-		if b.debug {
-			fmt.Printf("\tDebug: Adding ConfigMap %s to %s\n", cm.Name, workload.GetName())
-		}
-		cmNode := &Resource{
-			Kind:     "ConfigMap",
-			Name:     cm.Name,
-			Children: make([]*Resource, 0),
-		}
-		workloadNode.Children = append(workloadNode.Children, cmNode)
+	for i := range pod.Spec.Containers {
+		container := &pod.Spec.Containers[i]
+		containerNode := b.newContainerNode("Container", container, container, pod.Status.ContainerStatuses)
+		b.addContainerUsage(containerNode, container, pod)
+		podNode.Children = append(podNode.Children, containerNode)
 	}
 
-	// Add Secrets
-	for _, secret := range secrets {
-		if b.debug {
-			fmt.Printf("\tDebug: Adding Secret %s to %s\n", secret.Name, workload.GetName())
+	// Ephemeral containers come from debugging sessions such as kubectl debug
+	for i := range pod.Spec.EphemeralContainers {
+		ephemeral := &pod.Spec.EphemeralContainers[i]
+		container := corev1.Container(ephemeral.EphemeralContainerCommon)
+		ephemeralNode := b.newContainerNode("EphemeralContainer", ephemeral, &container, pod.Status.EphemeralContainerStatuses)
+		if ephemeral.TargetContainerName != "" {
+			ephemeralNode.Details = append(ephemeralNode.Details, "target="+ephemeral.TargetContainerName)
 		}
-		secretNode := &Resource{
-			Kind:     "Secret",
-			Name:     secret.Name,
-			Children: make([]*Resource, 0),
-		}
-		workloadNode.Children = append(workloadNode.Children, secretNode)
+		podNode.Children = append(podNode.Children, ephemeralNode)
+	}
+
+	return podNode
+}
+
+// addRelatedResources adds the objects found by the registered resolvers as
+// children of the workload node
+func (b *Builder) addRelatedResources(workload metav1.Object, workloadNode *Resource) {
+	edges := b.resources.Resolve(workload)
+
+	if b.options.Debug {
+		fmt.Fprintf(os.Stderr, "Debug: Found %d related resources for %s\n", len(edges), workload.GetName())
 	}
 
-	// Add PVCs
-	for _, pvc := range pvcs {
-		if b.debug {
-			fmt.Printf("\tDebug: Adding PVC %s to %s\n", pvc.Name, workload.GetName())
+	for _, edge := range edges {
+		if b.options.Debug {
+			fmt.Fprintf(os.Stderr, "\tDebug: Adding %s %s (%s) to %s\n", edge.Kind, edge.Object.GetName(), edge.Type, workload.GetName())
 		}
-		pvcNode := &Resource{
-			Kind:     "PersistentVolumeClaim",
-			Name:     pvc.Name,
+		workloadNode.Children = append(workloadNode.Children, b.newEdgeNode(edge))
+	}
+}
+
+// newEdgeNode creates the node for a related object, using the kind's own
+// constructor where there is one, and records the edge type on it. Guessed
+// edges are labelled as such.
+func (b *Builder) newEdgeNode(edge k8s.Edge) *Resource {
+	var details []string
+	if edge.Type == k8s.EdgeGuess {
+		details = append(details, "guess")
+	}
+
+	var node *Resource
+	switch obj := edge.Object.(type) {
+	case *corev1.Service:
+		node = b.newServiceNode(obj, details...)
+	case *corev1.PersistentVolumeClaim:
+		node = b.newPVCNode(obj, details...)
+	default:
+		node = &Resource{
+			Kind:     edge.Kind,
+			Name:     edge.Object.GetName(),
+			Object:   edge.Object,
+			Details:  details,
 			Children: make([]*Resource, 0),
 		}
-		workloadNode.Children = append(workloadNode.Children, pvcNode)
 	}
+	node.Edge = edge.Type
+	return node
 }
 
 // NewBuilder creates a new tree builder
-func NewBuilder(client *k8s.Client, debug bool) *Builder {
+func NewBuilder(client *k8s.Client, options Options) *Builder {
 	return &Builder{
-		client:    client,
-		debug:     debug,
-		resources: nil, // Will be set in BuildTree
+		client:     client,
+		options:    options,
+		resources:  nil, // Will be set in BuildTree
+		placed:     make(map[types.UID]bool),
 	}
 }
@@ -0,0 +1,112 @@
+package tree
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"kubectl-tree/pkg/k8s"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// BuildFunc builds the tree for a namespace, e.g. (*Builder).BuildTree
+type BuildFunc func(b *Builder, namespace string) (*Resource, error)
+
+// BuildClusterTree builds the tree for a namespace in each kubeconfig context
+// concurrently, with one client per context, and puts a Cluster node per
+// context under the root. A context that cannot be reached is shown with its
+// error rather than failing the whole tree; the errors of all such contexts
+// are returned together.
+func BuildClusterTree(kubeconfig string, contexts []string, namespace string, options Options, build BuildFunc) (*Resource, error) {
+	clusters := make([]*Resource, len(contexts))
+	errs := make([]error, len(contexts))
+	var wg sync.WaitGroup
+	for i, context := range contexts {
+		wg.Add(1)
+		go func(i int, context string) {
+			defer wg.Done()
+			clusters[i], errs[i] = buildClusterNode(kubeconfig, context, namespace, options, build)
+		}(i, context)
+	}
+	wg.Wait()
+
+	root := &Resource{
+		Kind:     "Namespace",
+		Name:     namespace,
+		Details:  []string{fmt.Sprintf("clusters=%d", len(contexts))},
+		Children: clusters,
+	}
+	return root, errors.Join(errs...)
+}
+
+// buildClusterNode builds the tree for one context and hangs what is in the
+// namespace under a Cluster node labelled with a pod readiness summary. The
+// error that kept the tree from being built, if any, is shown on the node
+// and returned.
+func buildClusterNode(kubeconfig, context, namespace string, options Options, build BuildFunc) (*Resource, error) {
+	node := &Resource{
+		Kind:     "Cluster",
+		Name:     context,
+		Children: make([]*Resource, 0),
+	}
+
+	client, err := k8s.NewClientForContext(kubeconfig, context)
+	if err != nil {
+		node.Details = append(node.Details, "error: "+err.Error())
+		return node, fmt.Errorf("%s: %w", context, err)
+	}
+	if err := client.NamespaceExists(namespace); err != nil {
+		if k8s.ErrorReason(err) == k8s.ReasonNotFound {
+			node.Details = append(node.Details, "namespace not found")
+			return node, fmt.Errorf("%s: namespace %q not found: %w", context, namespace, err)
+		}
+		node.Details = append(node.Details, "error: "+err.Error())
+		return node, fmt.Errorf("%s: %w", context, err)
+	}
+
+	root, err := build(NewBuilder(client, options), namespace)
+	var empty *EmptyError
+	if errors.As(err, &empty) {
+		node.Details = append(node.Details, "no "+empty.What)
+		return node, nil
+	}
+	if err != nil {
+		node.Details = append(node.Details, "error: "+err.Error())
+		return node, fmt.Errorf("%s: %w", context, err)
+	}
+	if root == nil {
+		// Nothing to report, e.g. no orphans or no drift
+		node.Details = append(node.Details, "nothing found")
+		return node, nil
+	}
+
+	node.Details = append(node.Details, podSummary(root))
+	node.Details = append(node.Details, root.Details...)
+	node.Usage = root.Usage
+	node.Children = root.Children
+	return node, nil
+}
+
+// podSummary counts the ready pods in a tree, so a cluster that has drifted
+// or is unhealthy stands out beside the others
+func podSummary(root *Resource) string {
+	ready, total := 0, 0
+	var walk func(node *Resource)
+	walk = func(node *Resource) {
+		if pod, ok := node.Object.(*corev1.Pod); ok {
+			total++
+			for _, condition := range pod.Status.Conditions {
+				if condition.Type == corev1.PodReady && condition.Status == corev1.ConditionTrue {
+					ready++
+					break
+				}
+			}
+		}
+		for _, child := range node.Children {
+			walk(child)
+		}
+	}
+	walk(root)
+	return fmt.Sprintf("pods ready=%d/%d", ready, total)
+}
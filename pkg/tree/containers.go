@@ -0,0 +1,56 @@
+package tree
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// newContainerNode creates a node for any kind of container, labelled with its
+// state from the matching status. The object is what the node shows, which for
+// ephemeral containers differs from the container spec used for details.
+func (b *Builder) newContainerNode(kind string, object interface{}, container *corev1.Container, statuses []corev1.ContainerStatus) *Resource {
+	containerNode := &Resource{
+		Kind:     kind,
+		Name:     container.Name,
+		Object:   object,
+		Children: make([]*Resource, 0),
+	}
+
+	for i := range statuses {
+		if statuses[i].Name == container.Name {
+			containerNode.Details = containerStatusDetails(&statuses[i])
+			break
+		}
+	}
+
+	if b.options.Wide {
+		containerNode.Details = append(containerNode.Details, containerDetails(container, statuses)...)
+	}
+
+	return containerNode
+}
+
+// containerStatusDetails describes a container's current state, readiness and restarts
+func containerStatusDetails(status *corev1.ContainerStatus) []string {
+	var details []string
+
+	switch {
+	case status.State.Running != nil:
+		if status.Ready {
+			details = append(details, "running")
+		} else {
+			details = append(details, "running, not ready")
+		}
+	case status.State.Waiting != nil:
+		details = append(details, "waiting: "+status.State.Waiting.Reason)
+	case status.State.Terminated != nil:
+		details = append(details, fmt.Sprintf("terminated: %s (exit %d)", status.State.Terminated.Reason, status.State.Terminated.ExitCode))
+	}
+
+	if status.RestartCount > 0 {
+		details = append(details, fmt.Sprintf("restarts=%d", status.RestartCount))
+	}
+
+	return details
+}
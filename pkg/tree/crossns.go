@@ -0,0 +1,231 @@
+package tree
+
+import (
+	"fmt"
+	"os"
+
+	"kubectl-tree/pkg/k8s"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// newCrossNamespaceNode creates the CrossNamespace/References section for
+// references that leave the namespace: Ingresses through ExternalName
+// Services, other ExternalName Services, RoleBindings to ServiceAccounts
+// elsewhere, and ReferenceGrants with the objects they admit. Foreign objects
+// are fetched as they are needed. It returns nil if there is nothing to show.
+func (b *Builder) newCrossNamespaceNode(namespace string) *Resource {
+	if !b.options.FollowCrossNamespace {
+		return nil
+	}
+
+	sectionNode := &Resource{
+		Kind:     "CrossNamespace",
+		Name:     "References",
+		Children: make([]*Resource, 0),
+	}
+
+	// Ingresses routing to ExternalName Services that point into another namespace
+	underIngress := make(map[string]bool)
+	ingresses, err := b.client.GetIngresses(namespace)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: ingresses unavailable: %v\n", err)
+	} else {
+		for i := range ingresses.Items {
+			ingress := &ingresses.Items[i]
+			ingressNode := &Resource{
+				Kind:     "Ingress",
+				Name:     ingress.Name,
+				Object:   ingress,
+				Children: make([]*Resource, 0),
+			}
+			for _, name := range k8s.IngressServiceNames(ingress) {
+				svc := b.resources.GetService(name)
+				if svc == nil {
+					continue
+				}
+				if svcNode := b.newExternalNameNode(svc); svcNode != nil {
+					ingressNode.Children = append(ingressNode.Children, svcNode)
+					underIngress[svc.Name] = true
+				}
+			}
+			if len(ingressNode.Children) > 0 {
+				sectionNode.Children = append(sectionNode.Children, ingressNode)
+			}
+		}
+	}
+
+	for i := range b.resources.Services.Items {
+		svc := &b.resources.Services.Items[i]
+		if underIngress[svc.Name] {
+			continue
+		}
+		if svcNode := b.newExternalNameNode(svc); svcNode != nil {
+			sectionNode.Children = append(sectionNode.Children, svcNode)
+		}
+	}
+
+	bindings, err := b.client.GetRoleBindings(namespace)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: rolebindings unavailable: %v\n", err)
+	} else {
+		for i := range bindings.Items {
+			binding := &bindings.Items[i]
+			refs := k8s.ForeignServiceAccounts(binding)
+			if len(refs) == 0 {
+				continue
+			}
+			bindingNode := &Resource{
+				Kind:     "RoleBinding",
+				Name:     binding.Name,
+				Object:   binding,
+				Details:  []string{binding.RoleRef.Kind + "/" + binding.RoleRef.Name},
+				Children: make([]*Resource, 0),
+			}
+			for _, ref := range refs {
+				bindingNode.Children = append(bindingNode.Children, b.newForeignNode(ref))
+			}
+			sectionNode.Children = append(sectionNode.Children, bindingNode)
+		}
+	}
+
+	// ReferenceGrants are optional; a cluster without the Gateway API has none
+	if grants, err := b.client.GetCustomResources(namespace, k8s.ReferenceGrant); err == nil {
+		for _, grant := range grants {
+			sectionNode.Children = append(sectionNode.Children, b.newReferenceGrantNode(grant, namespace))
+		}
+	} else if b.options.Debug {
+		fmt.Fprintf(os.Stderr, "Debug: referencegrants unavailable: %v\n", err)
+	}
+
+	if len(sectionNode.Children) == 0 {
+		return nil
+	}
+	return sectionNode
+}
+
+// newExternalNameNode creates a node for an ExternalName Service that points at
+// a Service in another namespace, with that Service as a foreign child, or
+// returns nil for any other Service
+func (b *Builder) newExternalNameNode(svc *corev1.Service) *Resource {
+	targetNamespace, targetName, ok := k8s.ExternalNameTarget(svc)
+	if !ok || targetNamespace == svc.Namespace {
+		return nil
+	}
+
+	svcNode := &Resource{
+		Kind:     "Service",
+		Name:     svc.Name,
+		Object:   svc,
+		Details:  []string{"ExternalName=" + svc.Spec.ExternalName},
+		Children: make([]*Resource, 0),
+	}
+	target := k8s.ObjectRef{Kind: schema.GroupKind{Kind: "Service"}, Namespace: targetNamespace, Name: targetName}
+	svcNode.Children = append(svcNode.Children, b.newForeignNode(target))
+	return svcNode
+}
+
+// newReferenceGrantNode creates a ReferenceGrant node with, for each namespace
+// it admits references from, the objects there that reference this namespace
+func (b *Builder) newReferenceGrantNode(grant *unstructured.Unstructured, namespace string) *Resource {
+	grantNode := &Resource{
+		Kind:     "ReferenceGrant",
+		Name:     grant.GetName(),
+		Object:   grant,
+		Children: make([]*Resource, 0),
+	}
+
+	from, to := k8s.ReferenceGrantSpec(grant)
+	for _, target := range to {
+		name := target.Name
+		if name == "" {
+			name = "*"
+		}
+		grantNode.Details = append(grantNode.Details, "to "+target.Kind.Kind+"/"+name)
+	}
+
+	for _, source := range from {
+		objects, err := b.client.GetCustomResources(source.Namespace, source.Kind)
+		if err != nil {
+			grantNode.Children = append(grantNode.Children, &Resource{
+				Kind:      source.Kind.Kind,
+				Name:      "*",
+				Namespace: source.Namespace,
+				Details:   []string{"unavailable"},
+				Children:  make([]*Resource, 0),
+			})
+			continue
+		}
+
+		for _, obj := range objects {
+			var admitted []string
+			for _, ref := range k8s.GatewayRefsInto(obj, namespace) {
+				for _, target := range to {
+					if target.Admits(ref) {
+						admitted = append(admitted, "-> "+ref.Kind.Kind+"/"+ref.Name)
+						break
+					}
+				}
+			}
+			if len(admitted) == 0 {
+				continue
+			}
+			grantNode.Children = append(grantNode.Children, &Resource{
+				Kind:      source.Kind.Kind,
+				Name:      obj.GetName(),
+				Namespace: source.Namespace,
+				Object:    obj,
+				Details:   admitted,
+				Children:  make([]*Resource, 0),
+			})
+		}
+	}
+	return grantNode
+}
+
+// newForeignNode creates a node for a Service or ServiceAccount in another
+// namespace, fetched once per build and labelled if it does not exist
+func (b *Builder) newForeignNode(ref k8s.ObjectRef) *Resource {
+	node := &Resource{
+		Kind:      ref.Kind.Kind,
+		Name:      ref.Name,
+		Namespace: ref.Namespace,
+		Children:  make([]*Resource, 0),
+	}
+
+	key := ref.Kind.Kind + "/" + ref.Namespace + "/" + ref.Name
+	obj, cached := b.foreign[key]
+	if !cached {
+		var err error
+		switch ref.Kind.Kind {
+		case "Service":
+			obj, err = b.client.GetService(ref.Namespace, ref.Name)
+		case "ServiceAccount":
+			obj, err = b.client.GetServiceAccount(ref.Namespace, ref.Name)
+		}
+		switch {
+		case apierrors.IsNotFound(err):
+			obj = nil
+		case err != nil:
+			node.Details = append(node.Details, "unavailable")
+			return node
+		}
+		b.foreign[key] = obj
+	}
+
+	if obj == nil {
+		node.Details = append(node.Details, "not found")
+		return node
+	}
+	node.Object = obj
+	if svc, ok := obj.(*corev1.Service); ok {
+		node.Details = append(node.Details, string(svc.Spec.Type))
+		if svc.Spec.ClusterIP != "" && svc.Spec.ClusterIP != corev1.ClusterIPNone {
+			node.Details = append(node.Details, svc.Spec.ClusterIP)
+		}
+	}
+	return node
+}
@@ -0,0 +1,52 @@
+package tree
+
+import (
+	"fmt"
+	"os"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// loadCustomResources fetches the custom kinds named by relationship rules,
+// keeping only kinds with objects. A kind the cluster does not serve is
+// skipped with a warning.
+func (b *Builder) loadCustomResources(namespace string) {
+	if b.options.Rules == nil {
+		return
+	}
+
+	b.resources.Custom = make(map[schema.GroupKind][]*unstructured.Unstructured)
+	for _, gk := range b.options.Rules.CustomKinds() {
+		objects, err := b.client.GetCustomResources(namespace, gk)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s unavailable: %v\n", gk, err)
+			continue
+		}
+		if len(objects) > 0 {
+			b.resources.Custom[gk] = objects
+		}
+	}
+}
+
+// addCustomResources adds the objects of each custom kind that rules draw edges
+// from, with the objects they reference as children
+func (b *Builder) addCustomResources(root *Resource) {
+	if b.options.Rules == nil {
+		return
+	}
+
+	for _, gk := range b.options.Rules.SourceKinds() {
+		for _, obj := range b.resources.Custom[gk] {
+			node := &Resource{
+				Kind:     gk.Kind,
+				Name:     obj.GetName(),
+				Object:   obj,
+				Children: make([]*Resource, 0),
+			}
+			root.Children = append(root.Children, node)
+
+			b.addRelatedResources(obj, node)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package tree
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"kubectl-tree/pkg/k8s"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// driftKinds are the kinds compared by BuildDriftTree, in the order they are listed
+var driftKinds = []schema.GroupKind{
+	{Group: "apps", Kind: "Deployment"},
+	{Group: "apps", Kind: "StatefulSet"},
+	{Group: "apps", Kind: "DaemonSet"},
+	{Group: "batch", Kind: "CronJob"},
+	{Group: "batch", Kind: "Job"},
+	{Kind: "ReplicationController"},
+	{Kind: "Service"},
+	{Kind: "ConfigMap"},
+	{Kind: "Secret"},
+}
+
+// BuildDriftTree builds one tree from manifests and another from the live
+// namespace, and returns a tree of the workloads, ConfigMaps, Secrets and
+// Services that exist on only one side or whose fields differ. Every such
+// object in the manifests is compared; on the live side only those the live
+// tree reaches from its workloads are, so system objects do not show up as
+// drift. Objects created by a controller, such as a CronJob's Jobs, are left out.
+func (b *Builder) BuildDriftTree(namespace string, manifests *k8s.Resources) (*Resource, error) {
+	live, err := b.client.GetResources(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	desired := make(map[string]metav1.Object)
+	collectDriftObjects(b.treeFrom(namespace, manifests), desired)
+	for _, gk := range driftKinds {
+		for _, obj := range manifests.ObjectsOfKind(gk) {
+			desired[gk.Kind+"/"+obj.GetName()] = obj
+		}
+	}
+
+	actual := make(map[string]metav1.Object)
+	collectDriftObjects(b.treeFrom(namespace, live), actual)
+
+	root := &Resource{
+		Kind:     "Namespace",
+		Name:     namespace,
+		Children: make([]*Resource, 0),
+	}
+	keys := make(map[string]bool)
+	for key := range desired {
+		keys[key] = true
+	}
+	for key := range actual {
+		keys[key] = true
+	}
+	for _, gk := range driftKinds {
+		var names []string
+		for key := range keys {
+			if name, ok := strings.CutPrefix(key, gk.Kind+"/"); ok {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			key := gk.Kind + "/" + name
+			desiredObj := desired[key]
+			liveObj := actual[key]
+			if liveObj == nil {
+				// Named in the manifests but not reached from a live workload
+				liveObj = live.GetObject(gk, name)
+			}
+			if node := newDriftNode(gk.Kind, name, desiredObj, liveObj); node != nil {
+				root.Children = append(root.Children, node)
+			}
+		}
+	}
+
+	if len(root.Children) == 0 {
+		fmt.Fprintf(os.Stderr, "No drift found in %s namespace.\n", namespace)
+		return nil, nil
+	}
+	return root, nil
+}
+
+// treeFrom builds a plain tree from the given resources, without metrics,
+// events, grouping or anything else fetched alongside them
+func (b *Builder) treeFrom(namespace string, resources *k8s.Resources) *Resource {
+	builder := NewBuilder(b.client, Options{Debug: b.options.Debug})
+	builder.resources = resources
+	return builder.buildTree(namespace)
+}
+
+// collectDriftObjects gathers the objects of the compared kinds in a tree,
+// keyed by kind and name, skipping foreign objects and those with a controller
+func collectDriftObjects(node *Resource, objects map[string]metav1.Object) {
+	if obj := nodeMeta(node); obj != nil && node.Namespace == "" && metav1.GetControllerOf(obj) == nil {
+		for _, gk := range driftKinds {
+			if node.Kind == gk.Kind {
+				objects[gk.Kind+"/"+obj.GetName()] = obj
+				break
+			}
+		}
+	}
+	for _, child := range node.Children {
+		collectDriftObjects(child, objects)
+	}
+}
+
+// newDriftNode creates a node for an object that exists on one side only, or
+// whose fields differ, with a child per differing field. It returns nil if
+// the object has not drifted.
+func newDriftNode(kind, name string, desired, live metav1.Object) *Resource {
+	node := &Resource{
+		Kind:     kind,
+		Name:     name,
+		Children: make([]*Resource, 0),
+	}
+	switch {
+	case live == nil:
+		node.Object = desired
+		node.Details = []string{"only in manifests"}
+		return node
+	case desired == nil:
+		node.Object = live
+		node.Details = []string{"only in cluster"}
+		return node
+	}
+
+	node.Object = live
+	for _, diff := range k8s.DiffObjects(desired, live) {
+		node.Children = append(node.Children, &Resource{
+			Kind:     "Field",
+			Name:     diff.Path,
+			Details:  []string{"manifest=" + driftValue(diff.Desired), "cluster=" + driftValue(diff.Live)},
+			Children: make([]*Resource, 0),
+		})
+	}
+	if len(node.Children) == 0 {
+		return nil
+	}
+	node.Details = []string{"changed"}
+	return node
+}
+
+// driftValue shows an absent value as such rather than as an empty string
+func driftValue(value string) string {
+	if value == "" {
+		return "<none>"
+	}
+	return value
+}
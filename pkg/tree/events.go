@@ -0,0 +1,131 @@
+package tree
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+// eventWindow is how far back Warning events are shown
+const eventWindow = time.Hour
+
+// maxEventsPerNode caps how many events are attached to a single node
+const maxEventsPerNode = 5
+
+// addEvents attaches recent Warning events to the nodes they concern, matching
+// on the involved object's UID. Events about a single container go under that
+// container's node rather than the pod.
+func (b *Builder) addEvents(root *Resource, namespace string) {
+	if !b.options.Events {
+		return
+	}
+
+	events, err := b.client.GetEvents(namespace)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: events unavailable: %v\n", err)
+		return
+	}
+
+	cutoff := time.Now().Add(-eventWindow)
+	eventsByUID := make(map[types.UID][]*corev1.Event)
+	for i := range events.Items {
+		event := &events.Items[i]
+		if event.Type != corev1.EventTypeWarning || eventLastSeen(event).Before(cutoff) {
+			continue
+		}
+		eventsByUID[event.InvolvedObject.UID] = append(eventsByUID[event.InvolvedObject.UID], event)
+	}
+	if len(eventsByUID) == 0 {
+		return
+	}
+
+	var attach func(node *Resource)
+	attach = func(node *Resource) {
+		// Recurse first so event nodes are not visited
+		for _, child := range node.Children {
+			attach(child)
+		}
+
+		meta, ok := node.Object.(metav1.Object)
+		if !ok {
+			return
+		}
+		nodeEvents := eventsByUID[meta.GetUID()]
+		sort.Slice(nodeEvents, func(i, j int) bool {
+			return eventLastSeen(nodeEvents[i]).After(eventLastSeen(nodeEvents[j]))
+		})
+		if len(nodeEvents) > maxEventsPerNode {
+			nodeEvents = nodeEvents[:maxEventsPerNode]
+		}
+
+		for _, event := range nodeEvents {
+			target := node
+			if container := findContainerNode(node, event.InvolvedObject.FieldPath); container != nil {
+				target = container
+			}
+			target.Children = append(target.Children, newEventNode(event))
+		}
+	}
+	attach(root)
+}
+
+// findContainerNode returns the container child named by an event field path
+// such as spec.containers{app}
+func findContainerNode(podNode *Resource, fieldPath string) *Resource {
+	open := strings.Index(fieldPath, "{")
+	if open < 0 || !strings.HasSuffix(fieldPath, "}") {
+		return nil
+	}
+	name := fieldPath[open+1 : len(fieldPath)-1]
+
+	for _, child := range podNode.Children {
+		if strings.HasSuffix(child.Kind, "Container") && child.Name == name {
+			return child
+		}
+	}
+	return nil
+}
+
+// newEventNode creates an Event node named by reason, with count, age and message
+func newEventNode(event *corev1.Event) *Resource {
+	count := event.Count
+	if event.Series != nil {
+		count = event.Series.Count
+	}
+	if count == 0 {
+		count = 1
+	}
+
+	return &Resource{
+		Kind:   "Event",
+		Name:   event.Reason,
+		Object: event,
+		Details: []string{
+			fmt.Sprintf("x%d", count),
+			duration.HumanDuration(time.Since(eventLastSeen(event))) + " ago",
+			strings.TrimSpace(event.Message),
+		},
+		Children: make([]*Resource, 0),
+	}
+}
+
+// eventLastSeen returns when an event last occurred, whichever timestamp is set
+func eventLastSeen(event *corev1.Event) time.Time {
+	switch {
+	case event.Series != nil && !event.Series.LastObservedTime.IsZero():
+		return event.Series.LastObservedTime.Time
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	default:
+		return event.CreationTimestamp.Time
+	}
+}
@@ -0,0 +1,126 @@
+package tree
+
+import (
+	"fmt"
+	"os"
+
+	"kubectl-tree/pkg/k8s"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// groupByGitOps makes each Argo CD Application, Flux Kustomization or Flux
+// HelmRelease the parent of the workloads it applied
+func (b *Builder) groupByGitOps(root *Resource) {
+	owners := make(map[string]*k8s.GitOpsOwner)
+	regroup(root, make(map[string]*Resource), func(node *Resource) string {
+		meta := nodeMeta(node)
+		if meta == nil {
+			return ""
+		}
+		owner := k8s.GitOpsOwnerOf(meta)
+		if owner == nil {
+			return ""
+		}
+		owners[owner.Key()] = owner
+		return owner.Key()
+	}, func(key string) *Resource {
+		return b.newGitOpsNode(owners[key], root.Name)
+	})
+}
+
+// newGitOpsNode creates the node for a GitOps owner, labelled with its status.
+// The owner often lives in another namespace, which is shown when it differs.
+func (b *Builder) newGitOpsNode(owner *k8s.GitOpsOwner, namespace string) *Resource {
+	node := &Resource{
+		Kind:     owner.Kind.Kind,
+		Name:     owner.Name,
+		Children: make([]*Resource, 0),
+	}
+	if owner.Namespace != namespace {
+		node.Details = append(node.Details, "namespace="+owner.Namespace)
+	}
+
+	obj, err := b.client.GetCustomResource(owner.Kind, owner.Namespace, owner.Name)
+	switch {
+	case apierrors.IsNotFound(err):
+		node.Details = append(node.Details, "not found")
+		return node
+	case err != nil:
+		if b.options.Debug {
+			fmt.Fprintf(os.Stderr, "Debug: %s %s/%s unavailable: %v\n", owner.Kind, owner.Namespace, owner.Name, err)
+		}
+		node.Details = append(node.Details, "status unavailable")
+		return node
+	}
+
+	node.Object = obj
+	if owner.Kind == k8s.ArgoApplication {
+		node.Details = append(node.Details, argoStatusDetails(obj)...)
+	} else {
+		node.Details = append(node.Details, fluxStatusDetails(obj)...)
+	}
+	return node
+}
+
+// argoStatusDetails describes an Application's sync and health status
+func argoStatusDetails(app *unstructured.Unstructured) []string {
+	var details []string
+	if sync, _, _ := unstructured.NestedString(app.Object, "status", "sync", "status"); sync != "" {
+		details = append(details, "sync="+sync)
+	}
+	if health, _, _ := unstructured.NestedString(app.Object, "status", "health", "status"); health != "" {
+		details = append(details, "health="+health)
+	}
+	if revision, _, _ := unstructured.NestedString(app.Object, "status", "sync", "revision"); revision != "" {
+		details = append(details, "revision="+shortRevision(revision))
+	}
+	return details
+}
+
+// fluxStatusDetails describes a Flux object's Ready condition and applied revision
+func fluxStatusDetails(obj *unstructured.Unstructured) []string {
+	var details []string
+	if suspended, _, _ := unstructured.NestedBool(obj.Object, "spec", "suspend"); suspended {
+		details = append(details, "suspended")
+	}
+
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok || condition["type"] != "Ready" {
+			continue
+		}
+		switch condition["status"] {
+		case "True":
+			details = append(details, "ready")
+		case "False":
+			details = append(details, fmt.Sprintf("not ready: %v", condition["reason"]))
+		default:
+			details = append(details, "reconciling")
+		}
+	}
+
+	revision, _, _ := unstructured.NestedString(obj.Object, "status", "lastAppliedRevision")
+	if revision == "" {
+		// HelmReleases record the chart version they last installed
+		revision, _, _ = unstructured.NestedString(obj.Object, "status", "lastAttemptedRevision")
+	}
+	if revision != "" {
+		details = append(details, "revision="+shortRevision(revision))
+	}
+	return details
+}
+
+// shortRevision shortens git commit revisions, which Flux prefixes with the
+// branch, e.g. main@sha1:0123abcd...
+func shortRevision(revision string) string {
+	if at := len(revision) - 40; at > 0 && revision[at-1] == ':' {
+		return revision[:at+7]
+	}
+	if len(revision) == 40 {
+		return revision[:7]
+	}
+	return revision
+}
@@ -0,0 +1,118 @@
+package tree
+
+import (
+	"fmt"
+	"sort"
+
+	"kubectl-tree/pkg/k8s"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Grouping modes for Options.GroupBy
+const (
+	GroupByHelm   = "helm"   // Under the Helm release that installed them
+	GroupByGitOps = "gitops" // Under the Argo CD Application or Flux object that applied them
+	GroupByApp    = "app"    // By the app.kubernetes.io recommended labels
+)
+
+// GroupByModes lists the accepted values of Options.GroupBy
+var GroupByModes = []string{GroupByHelm, GroupByGitOps, GroupByApp}
+
+// groupTree adds a grouping layer above the top-level nodes when one is requested
+func (b *Builder) groupTree(root *Resource) {
+	switch b.options.GroupBy {
+	case GroupByHelm:
+		b.groupByHelm(root)
+	case GroupByGitOps:
+		b.groupByGitOps(root)
+	case GroupByApp:
+		b.groupByApp(root)
+	}
+}
+
+// regroup moves each child of parent under the group node for its key. Groups
+// already in the map are kept even when empty; missing ones are made by
+// newGroup. Groups come first, sorted by key, followed by the children with
+// no key in their original order.
+func regroup(parent *Resource, groups map[string]*Resource, groupKey func(node *Resource) string, newGroup func(key string) *Resource) {
+	var ungrouped []*Resource
+	for _, child := range parent.Children {
+		key := groupKey(child)
+		if key == "" {
+			ungrouped = append(ungrouped, child)
+			continue
+		}
+		group, ok := groups[key]
+		if !ok {
+			group = newGroup(key)
+			groups[key] = group
+		}
+		group.Children = append(group.Children, child)
+	}
+
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	children := make([]*Resource, 0, len(keys)+len(ungrouped))
+	for _, key := range keys {
+		children = append(children, groups[key])
+	}
+	parent.Children = append(children, ungrouped...)
+}
+
+// nodeMeta returns the object metadata behind a node, or nil for section nodes
+func nodeMeta(node *Resource) metav1.Object {
+	meta, _ := node.Object.(metav1.Object)
+	return meta
+}
+
+// groupByHelm makes each Helm release the parent of the workloads it installed.
+// Releases come from their release Secrets, so a release whose workloads are
+// all gone still appears.
+func (b *Builder) groupByHelm(root *Resource) {
+	groups := make(map[string]*Resource)
+	for name, release := range b.resources.GetHelmReleases() {
+		groups[name] = newHelmReleaseNode(release)
+	}
+
+	regroup(root, groups, func(node *Resource) string {
+		if meta := nodeMeta(node); meta != nil {
+			return k8s.HelmReleaseName(meta)
+		}
+		return ""
+	}, func(name string) *Resource {
+		// Without a release Secret only the name is known
+		return &Resource{
+			Kind:     "HelmRelease",
+			Name:     name,
+			Details:  []string{"no release record"},
+			Children: make([]*Resource, 0),
+		}
+	})
+}
+
+// newHelmReleaseNode creates a HelmRelease node labelled with its chart,
+// revision and status
+func newHelmReleaseNode(release *k8s.HelmRelease) *Resource {
+	node := &Resource{
+		Kind:     "HelmRelease",
+		Name:     release.Name,
+		Object:   release.Secret,
+		Children: make([]*Resource, 0),
+	}
+	if release.Chart != "" {
+		node.Details = append(node.Details, fmt.Sprintf("chart=%s-%s", release.Chart, release.ChartVersion))
+	}
+	if release.AppVersion != "" {
+		node.Details = append(node.Details, "app="+release.AppVersion)
+	}
+	node.Details = append(node.Details, fmt.Sprintf("revision=%d", release.Revision))
+	if release.Status != "" {
+		node.Details = append(node.Details, release.Status)
+	}
+	return node
+}
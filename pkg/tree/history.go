@@ -0,0 +1,134 @@
+package tree
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"kubectl-tree/pkg/k8s"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// changeCauseAnnotation records why a revision was made, e.g. by kubectl annotate
+const changeCauseAnnotation = "kubernetes.io/change-cause"
+
+// sortReplicaSetsByRevision orders ReplicaSets newest revision first
+func sortReplicaSetsByRevision(replicaSets []*appsv1.ReplicaSet) {
+	sort.Slice(replicaSets, func(i, j int) bool {
+		return k8s.ReplicaSetRevision(replicaSets[i]) > k8s.ReplicaSetRevision(replicaSets[j])
+	})
+}
+
+// replicaSetHistoryDetails labels a Deployment's ReplicaSets with their revision.
+// The ReplicaSets must be sorted newest first; the newest is the current one.
+func replicaSetHistoryDetails(replicaSets []*appsv1.ReplicaSet, i int) []string {
+	rs := replicaSets[i]
+	var previous []corev1.Container
+	if i+1 < len(replicaSets) {
+		previous = replicaSets[i+1].Spec.Template.Spec.Containers
+	}
+	return revisionDetails(
+		k8s.ReplicaSetRevision(rs),
+		i == 0,
+		rs.Annotations[changeCauseAnnotation],
+		rs.Spec.Template.Spec.Containers,
+		previous,
+		i+1 < len(replicaSets),
+	)
+}
+
+// addControllerRevisions adds a StatefulSet's or DaemonSet's ControllerRevisions,
+// newest first, labelled like a Deployment's ReplicaSets
+func (b *Builder) addControllerRevisions(workloadNode *Resource, workload metav1.Object, currentRevision string) {
+	revisions := b.resources.GetControllerRevisionsByOwner(workload)
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Revision > revisions[j].Revision
+	})
+
+	containers := make([][]corev1.Container, len(revisions))
+	for i, cr := range revisions {
+		containers[i] = revisionContainers(cr)
+	}
+
+	for i, cr := range revisions {
+		// Without a recorded current revision, the newest one is current
+		current := cr.Name == currentRevision || (currentRevision == "" && i == 0)
+
+		var previous []corev1.Container
+		if i+1 < len(revisions) {
+			previous = containers[i+1]
+		}
+
+		workloadNode.Children = append(workloadNode.Children, &Resource{
+			Kind:     "ControllerRevision",
+			Name:     cr.Name,
+			Object:   cr,
+			Details:  revisionDetails(cr.Revision, current, cr.Annotations[changeCauseAnnotation], containers[i], previous, i+1 < len(revisions)),
+			Children: make([]*Resource, 0),
+		})
+	}
+}
+
+// revisionContainers decodes the pod template containers stored in a ControllerRevision
+func revisionContainers(cr *appsv1.ControllerRevision) []corev1.Container {
+	var data struct {
+		Spec struct {
+			Template corev1.PodTemplateSpec `json:"template"`
+		} `json:"spec"`
+	}
+	if err := json.Unmarshal(cr.Data.Raw, &data); err != nil {
+		return nil
+	}
+	return data.Spec.Template.Spec.Containers
+}
+
+// revisionDetails describes a revision and what its images change from the previous one
+func revisionDetails(revision int64, current bool, changeCause string, containers, previous []corev1.Container, hasPrevious bool) []string {
+	details := []string{fmt.Sprintf("revision=%d", revision)}
+	if current {
+		details = append(details, "current")
+	}
+	if changeCause != "" {
+		details = append(details, "change-cause="+changeCause)
+	}
+	if hasPrevious {
+		if changes := imageChanges(previous, containers); len(changes) > 0 {
+			details = append(details, "images: "+strings.Join(changes, "; "))
+		} else {
+			details = append(details, "images unchanged")
+		}
+	}
+	return details
+}
+
+// imageChanges lists containers whose image differs between two revisions,
+// plus containers that were added or removed
+func imageChanges(before, after []corev1.Container) []string {
+	beforeImages := make(map[string]string, len(before))
+	for _, container := range before {
+		beforeImages[container.Name] = container.Image
+	}
+
+	var changes []string
+	seen := make(map[string]bool, len(after))
+	for _, container := range after {
+		seen[container.Name] = true
+		image, ok := beforeImages[container.Name]
+		switch {
+		case !ok:
+			changes = append(changes, fmt.Sprintf("+%s %s", container.Name, container.Image))
+		case image != container.Image:
+			changes = append(changes, fmt.Sprintf("%s %s -> %s", container.Name, image, container.Image))
+		}
+	}
+	for _, container := range before {
+		if !seen[container.Name] {
+			changes = append(changes, fmt.Sprintf("-%s", container.Name))
+		}
+	}
+	return changes
+}
@@ -0,0 +1,145 @@
+package tree
+
+import (
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Well-known node labels shown in the node placement view
+const (
+	zoneLabel         = "topology.kubernetes.io/zone"
+	instanceTypeLabel = "node.kubernetes.io/instance-type"
+)
+
+// BuildNodeTree builds a tree of the namespace's pods grouped by the node they run on
+func (b *Builder) BuildNodeTree(namespace string) (*Resource, error) {
+	resources, err := b.client.GetResources(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	b.resources = resources
+	if err := b.loadNodes(); err != nil {
+		return nil, err
+	}
+
+	if len(resources.Pods.Items) == 0 {
+		return nil, &EmptyError{Namespace: namespace, What: "pods"}
+	}
+
+	b.loadMetrics(namespace)
+
+	root := &Resource{
+		Kind:     "Namespace",
+		Name:     namespace,
+		Children: make([]*Resource, 0),
+	}
+
+	// Group pods by node name, with unscheduled pods under an empty name
+	podsByNode := make(map[string][]*corev1.Pod)
+	for i := range resources.Pods.Items {
+		pod := &resources.Pods.Items[i]
+		podsByNode[pod.Spec.NodeName] = append(podsByNode[pod.Spec.NodeName], pod)
+	}
+
+	nodeNames := make([]string, 0, len(podsByNode))
+	for name := range podsByNode {
+		if name != "" {
+			nodeNames = append(nodeNames, name)
+		}
+	}
+	sort.Strings(nodeNames)
+
+	for _, name := range nodeNames {
+		nodeNode := b.newNodeNode(name)
+		for _, pod := range podsByNode[name] {
+			nodeNode.Children = append(nodeNode.Children, b.newPlacedPodNode(pod))
+		}
+		root.Children = append(root.Children, nodeNode)
+	}
+
+	if pending := podsByNode[""]; len(pending) > 0 {
+		unscheduledNode := &Resource{
+			Kind:     "Node",
+			Name:     "<none>",
+			Details:  []string{"unscheduled"},
+			Children: make([]*Resource, 0),
+		}
+		for _, pod := range pending {
+			unscheduledNode.Children = append(unscheduledNode.Children, b.newPlacedPodNode(pod))
+		}
+		root.Children = append(root.Children, unscheduledNode)
+	}
+
+	b.addEvents(root, namespace)
+
+	if b.podMetrics != nil {
+		rollupUsage(root)
+	}
+
+	return root, nil
+}
+
+// loadNodes adds the cluster's Nodes to the loaded resources. It must run
+// before anything looks the resources up, as the lookups are built once.
+func (b *Builder) loadNodes() error {
+	nodes, err := b.client.GetNodes()
+	if err != nil {
+		return err
+	}
+	b.resources.Nodes = nodes
+	return nil
+}
+
+// newNodeNode creates a Node node labelled with its zone, instance type,
+// problem conditions and taints
+func (b *Builder) newNodeNode(name string) *Resource {
+	nodeNode := &Resource{
+		Kind:     "Node",
+		Name:     name,
+		Children: make([]*Resource, 0),
+	}
+
+	// Without permission to list nodes only the name is known
+	node := b.resources.GetNode(name)
+	if node == nil {
+		return nodeNode
+	}
+	nodeNode.Object = node
+
+	if zone := node.Labels[zoneLabel]; zone != "" {
+		nodeNode.Details = append(nodeNode.Details, "zone="+zone)
+	}
+	if instanceType := node.Labels[instanceTypeLabel]; instanceType != "" {
+		nodeNode.Details = append(nodeNode.Details, "type="+instanceType)
+	}
+
+	for _, condition := range node.Status.Conditions {
+		switch {
+		case condition.Type == corev1.NodeReady && condition.Status != corev1.ConditionTrue:
+			nodeNode.Details = append(nodeNode.Details, "NotReady")
+		case condition.Type != corev1.NodeReady && condition.Status == corev1.ConditionTrue:
+			// Every other condition signals a problem when true
+			nodeNode.Details = append(nodeNode.Details, string(condition.Type))
+		}
+	}
+
+	if node.Spec.Unschedulable {
+		nodeNode.Details = append(nodeNode.Details, "cordoned")
+	}
+	for _, taint := range node.Spec.Taints {
+		nodeNode.Details = append(nodeNode.Details, "taint="+taint.ToString())
+	}
+
+	return nodeNode
+}
+
+// newPlacedPodNode creates a Pod node labelled with the workload that owns it
+func (b *Builder) newPlacedPodNode(pod *corev1.Pod) *Resource {
+	podNode := b.newPodNode(pod)
+	if detail := b.ownerDetail(pod); detail != "" {
+		podNode.Details = append(podNode.Details, detail)
+	}
+	return podNode
+}
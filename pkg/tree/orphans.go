@@ -0,0 +1,92 @@
+package tree
+
+import (
+	"fmt"
+	"os"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// BuildOrphanTree builds a tree of resources in the namespace that nothing references
+func (b *Builder) BuildOrphanTree(namespace string) (*Resource, error) {
+	resources, err := b.client.GetResources(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ingress certificates and ServiceAccount secrets are references too.
+	// Without permission to list them the lists stay nil and those Secrets
+	// may be reported.
+	resources.Ingresses, err = b.client.GetIngresses(namespace)
+	if apierrors.IsForbidden(err) {
+		fmt.Fprintf(os.Stderr, "Warning: ingresses unavailable, Secrets used only for Ingress TLS may be reported as orphans: %v\n", err)
+		resources.Ingresses = nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error fetching ingresses: %w", err)
+	}
+	resources.ServiceAccounts, err = b.client.GetServiceAccounts(namespace)
+	if apierrors.IsForbidden(err) {
+		fmt.Fprintf(os.Stderr, "Warning: serviceaccounts unavailable, Secrets used only by ServiceAccounts may be reported as orphans: %v\n", err)
+	} else if err != nil {
+		return nil, err
+	}
+
+	b.resources = resources
+	// Custom resources named by relationship rules may reference Secrets and
+	// ConfigMaps too
+	b.loadCustomResources(namespace)
+
+	orphans := resources.FindOrphans(b.options.Rules)
+	if orphans.IsEmpty() {
+		fmt.Fprintf(os.Stderr, "No orphaned resources found in %s namespace.\n", namespace)
+		return nil, nil
+	}
+
+	root := &Resource{
+		Kind:     "Namespace",
+		Name:     namespace,
+		Children: make([]*Resource, 0),
+	}
+
+	for _, svc := range orphans.Services {
+		root.Children = append(root.Children, &Resource{
+			Kind:     "Service",
+			Name:     svc.Name,
+			Object:   svc,
+			Children: make([]*Resource, 0),
+		})
+	}
+
+	for _, cm := range orphans.ConfigMaps {
+		root.Children = append(root.Children, &Resource{
+			Kind:     "ConfigMap",
+			Name:     cm.Name,
+			Object:   cm,
+			Children: make([]*Resource, 0),
+		})
+	}
+
+	for _, secret := range orphans.Secrets {
+		root.Children = append(root.Children, &Resource{
+			Kind:     "Secret",
+			Name:     secret.Name,
+			Object:   secret,
+			Children: make([]*Resource, 0),
+		})
+	}
+
+	for _, pvc := range orphans.PVCs {
+		root.Children = append(root.Children, b.newPVCNode(pvc))
+	}
+
+	for _, rs := range orphans.ReplicaSets {
+		root.Children = append(root.Children, &Resource{
+			Kind:     "ReplicaSet",
+			Name:     rs.Name,
+			Object:   rs,
+			Children: make([]*Resource, 0),
+		})
+	}
+
+	return root, nil
+}
@@ -0,0 +1,126 @@
+package tree
+
+import (
+	"fmt"
+	"kubectl-tree/pkg/k8s"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// mirrorPodAnnotation marks the API copy of a static pod run directly by a kubelet
+const mirrorPodAnnotation = "kubernetes.io/config.mirror"
+
+// newOtherNode creates the Unowned/Other section for ReplicationControllers with
+// their pods, standalone pods, and objects whose owner the builder does not
+// follow or whose owner reference is stale. It must run after the workloads are
+// added, and returns nil if there is nothing to show.
+func (b *Builder) newOtherNode() *Resource {
+	otherNode := &Resource{
+		Kind:     "Unowned",
+		Name:     "Other",
+		Children: make([]*Resource, 0),
+	}
+
+	for i := range b.resources.ReplicationControllers.Items {
+		rc := &b.resources.ReplicationControllers.Items[i]
+		rcNode := &Resource{
+			Kind:     "ReplicationController",
+			Name:     rc.Name,
+			Object:   rc,
+			Children: make([]*Resource, 0),
+		}
+		otherNode.Children = append(otherNode.Children, rcNode)
+
+		// Add related resources
+		b.addRelatedResources(rc, rcNode)
+
+		// Add Pods
+		for _, pod := range b.resources.GetPodsByOwner(rc) {
+			rcNode.Children = append(rcNode.Children, b.newPodNode(pod))
+		}
+	}
+
+	// ReplicaSets and Jobs whose owner is gone or was recreated, with their pods
+	for i := range b.resources.ReplicaSets.Items {
+		rs := &b.resources.ReplicaSets.Items[i]
+		if b.placed[rs.UID] {
+			continue
+		}
+		otherNode.Children = append(otherNode.Children, b.newUnplacedNode("ReplicaSet", rs))
+	}
+	for i := range b.resources.Jobs.Items {
+		job := &b.resources.Jobs.Items[i]
+		if b.placed[job.UID] {
+			continue
+		}
+		otherNode.Children = append(otherNode.Children, b.newUnplacedNode("Job", job))
+	}
+
+	for i := range b.resources.Pods.Items {
+		pod := &b.resources.Pods.Items[i]
+		if b.placed[pod.UID] {
+			continue
+		}
+
+		podNode := b.newPodNode(pod)
+		if detail := b.ownerDetail(pod); detail != "" {
+			podNode.Details = append(podNode.Details, detail)
+		} else {
+			podNode.Details = append(podNode.Details, "no owner")
+		}
+		if _, ok := pod.Annotations[mirrorPodAnnotation]; ok {
+			podNode.Details = append(podNode.Details, "mirror")
+		}
+		otherNode.Children = append(otherNode.Children, podNode)
+	}
+
+	if len(otherNode.Children) == 0 {
+		return nil
+	}
+	return otherNode
+}
+
+// newUnplacedNode creates a node for a ReplicaSet or Job that no workload in the
+// tree controls, labelled with its owner and holding the pods it controls
+func (b *Builder) newUnplacedNode(kind string, obj metav1.Object) *Resource {
+	node := &Resource{
+		Kind:     kind,
+		Name:     obj.GetName(),
+		Object:   obj,
+		Children: make([]*Resource, 0),
+	}
+	if detail := b.ownerDetail(obj); detail != "" {
+		node.Details = append(node.Details, detail)
+	} else {
+		node.Details = append(node.Details, "no owner")
+	}
+	b.placed[obj.GetUID()] = true
+
+	for _, pod := range b.resources.GetPodsByOwner(obj) {
+		node.Children = append(node.Children, b.newPodNode(pod))
+	}
+	return node
+}
+
+// ownerDetail describes an object's controller, or its first owner if none is a
+// controller, flagging references whose owner was deleted or recreated. It
+// returns an empty string for objects without owners.
+func (b *Builder) ownerDetail(obj metav1.Object) string {
+	owner := metav1.GetControllerOfNoCopy(obj)
+	if owner == nil {
+		refs := obj.GetOwnerReferences()
+		if len(refs) == 0 {
+			return ""
+		}
+		owner = &refs[0]
+	}
+
+	switch b.resources.CheckOwner(*owner) {
+	case k8s.OwnerMissing:
+		return fmt.Sprintf("stale owner=%s/%s, pending garbage collection", owner.Kind, owner.Name)
+	case k8s.OwnerRecreated:
+		return fmt.Sprintf("stale owner=%s/%s, recreated with a new UID", owner.Kind, owner.Name)
+	default:
+		return fmt.Sprintf("owner=%s/%s", owner.Kind, owner.Name)
+	}
+}
@@ -2,11 +2,13 @@ package tree
 
 import (
 	"fmt"
+	"strings"
 )
 
 // ANSI color codes
 const (
 	colorReset  = "\033[0m"
+	colorRed    = "\033[31m"
 	colorGreen  = "\033[32m"
 	colorYellow = "\033[33m"
 	colorBlue   = "\033[34m"
@@ -34,17 +36,38 @@ func (p *Printer) getResourceColor(kind string) string {
 		return colorBlue
 	case "Pod":
 		return colorGreen
-	case "Service":
+	case "Service", "EndpointSlice", "Endpoint":
 		return colorYellow
 	case "ConfigMap", "Secret":
 		return colorPurple
-	case "PersistentVolumeClaim":
+	case "PersistentVolumeClaim", "PersistentVolume", "StorageClass":
 		return colorCyan
+	case "Event":
+		return colorRed
 	default:
 		return ""
 	}
 }
 
+// formatUsage renders usage colored by how close it is to the limit
+func (p *Printer) formatUsage(usage *Usage) string {
+	if usage == nil {
+		return ""
+	}
+	if !p.useColor {
+		return " [" + usage.String() + "]"
+	}
+
+	color := colorGreen
+	switch percent := usage.Percent(); {
+	case percent >= usageCriticalPercent:
+		color = colorRed
+	case percent >= usageWarnPercent:
+		color = colorYellow
+	}
+	return " " + color + "[" + usage.String() + "]" + colorReset
+}
+
 func (p *Printer) getConnector(isLast bool) string {
 	if isLast {
 		return "└── "
@@ -58,13 +81,19 @@ func (p *Printer) PrintTree(node *Resource, prefix string, isLast bool) {
 	}
 
 	color := p.getResourceColor(node.Kind)
-	fmt.Printf("%s%s%s%s/%s%s\n",
+	details := ""
+	if nodeDetails := node.DisplayDetails(); len(nodeDetails) > 0 {
+		details = " (" + strings.Join(nodeDetails, ", ") + ")"
+	}
+	fmt.Printf("%s%s%s%s/%s%s%s%s\n",
 		prefix,
 		p.getConnector(isLast),
 		color,
 		node.Kind,
 		node.Name,
 		colorReset,
+		details,
+		p.formatUsage(node.Usage),
 	)
 
 	childPrefix := prefix
@@ -0,0 +1,93 @@
+package tree
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// newServiceNode creates a Service node with its EndpointSlices and their endpoints
+func (b *Builder) newServiceNode(svc *corev1.Service, details ...string) *Resource {
+	svcNode := &Resource{
+		Kind:     "Service",
+		Name:     svc.Name,
+		Object:   svc,
+		Details:  details,
+		Children: make([]*Resource, 0),
+	}
+
+	for _, slice := range b.resources.GetEndpointSlicesForService(svc.Name) {
+		sliceNode := &Resource{
+			Kind:     "EndpointSlice",
+			Name:     slice.Name,
+			Object:   slice,
+			Children: make([]*Resource, 0),
+		}
+
+		ready := 0
+		for _, endpoint := range slice.Endpoints {
+			endpointNode := b.newEndpointNode(endpoint)
+			if endpointReady(endpoint) {
+				ready++
+			}
+			sliceNode.Children = append(sliceNode.Children, endpointNode)
+		}
+		sliceNode.Details = []string{
+			string(slice.AddressType),
+			fmt.Sprintf("%d ready", ready),
+			fmt.Sprintf("%d not ready", len(slice.Endpoints)-ready),
+		}
+
+		svcNode.Children = append(svcNode.Children, sliceNode)
+	}
+
+	return svcNode
+}
+
+// newEndpointNode creates an Endpoint node named after its first address,
+// pointing back at the Pod it targets. A target Pod that is not in the
+// namespace, or was recreated since, is marked missing.
+func (b *Builder) newEndpointNode(endpoint discoveryv1.Endpoint) *Resource {
+	name := "<none>"
+	if len(endpoint.Addresses) > 0 {
+		name = endpoint.Addresses[0]
+	}
+
+	endpointNode := &Resource{
+		Kind:     "Endpoint",
+		Name:     name,
+		Object:   endpoint,
+		Children: make([]*Resource, 0),
+	}
+
+	if endpointReady(endpoint) {
+		endpointNode.Details = append(endpointNode.Details, "ready")
+	} else {
+		endpointNode.Details = append(endpointNode.Details, "not ready")
+	}
+	if endpoint.Conditions.Terminating != nil && *endpoint.Conditions.Terminating {
+		endpointNode.Details = append(endpointNode.Details, "terminating")
+	}
+	if endpoint.TargetRef != nil {
+		endpointNode.Details = append(endpointNode.Details, fmt.Sprintf("-> %s/%s", endpoint.TargetRef.Kind, endpoint.TargetRef.Name))
+		if endpoint.TargetRef.Kind == "Pod" {
+			pod := b.resources.GetObject(schema.GroupKind{Kind: "Pod"}, endpoint.TargetRef.Name)
+			if pod == nil || (endpoint.TargetRef.UID != "" && pod.GetUID() != endpoint.TargetRef.UID) {
+				endpointNode.Details = append(endpointNode.Details, "pod missing")
+			}
+		}
+	}
+	if endpoint.NodeName != nil {
+		endpointNode.Details = append(endpointNode.Details, "node="+*endpoint.NodeName)
+	}
+
+	return endpointNode
+}
+
+// endpointReady reports whether an endpoint receives traffic. A nil ready
+// condition means the state is unknown and is treated as ready.
+func endpointReady(endpoint discoveryv1.Endpoint) bool {
+	return endpoint.Conditions.Ready == nil || *endpoint.Conditions.Ready
+}
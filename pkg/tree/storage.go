@@ -0,0 +1,151 @@
+package tree
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// accessModeNames are the short forms kubectl uses for access modes
+var accessModeNames = map[corev1.PersistentVolumeAccessMode]string{
+	corev1.ReadWriteOnce:    "RWO",
+	corev1.ReadOnlyMany:     "ROX",
+	corev1.ReadWriteMany:    "RWX",
+	corev1.ReadWriteOncePod: "RWOP",
+}
+
+// newPVCNode creates a PVC node followed by its storage chain:
+// PersistentVolume, StorageClass, CSI driver and VolumeAttachments
+func (b *Builder) newPVCNode(pvc *corev1.PersistentVolumeClaim, details ...string) *Resource {
+	pvcNode := &Resource{
+		Kind:     "PersistentVolumeClaim",
+		Name:     pvc.Name,
+		Object:   pvc,
+		Details:  details,
+		Children: make([]*Resource, 0),
+	}
+
+	if pvc.Status.Phase != corev1.ClaimBound {
+		pvcNode.Details = append(pvcNode.Details, string(pvc.Status.Phase))
+	}
+	if storage, ok := pvc.Status.Capacity[corev1.ResourceStorage]; ok {
+		pvcNode.Details = append(pvcNode.Details, storage.String())
+	}
+	if modes := formatAccessModes(pvc.Status.AccessModes); modes != "" {
+		pvcNode.Details = append(pvcNode.Details, modes)
+	}
+
+	// An unbound claim still shows the StorageClass that should provision it
+	var pv *corev1.PersistentVolume
+	if pvc.Spec.VolumeName != "" {
+		pv = b.resources.GetPersistentVolume(pvc.Spec.VolumeName)
+	}
+	if pv == nil {
+		if pvc.Spec.StorageClassName != nil && *pvc.Spec.StorageClassName != "" {
+			pvcNode.Children = append(pvcNode.Children, b.newStorageClassNode(*pvc.Spec.StorageClassName, ""))
+		}
+		return pvcNode
+	}
+
+	pvNode := &Resource{
+		Kind:     "PersistentVolume",
+		Name:     pv.Name,
+		Object:   pv,
+		Children: make([]*Resource, 0),
+	}
+	if storage, ok := pv.Spec.Capacity[corev1.ResourceStorage]; ok {
+		pvNode.Details = append(pvNode.Details, storage.String())
+	}
+	if modes := formatAccessModes(pv.Spec.AccessModes); modes != "" {
+		pvNode.Details = append(pvNode.Details, modes)
+	}
+	pvNode.Details = append(pvNode.Details, "reclaim="+string(pv.Spec.PersistentVolumeReclaimPolicy))
+	if pv.Status.Phase != corev1.VolumeBound {
+		pvNode.Details = append(pvNode.Details, string(pv.Status.Phase))
+	}
+	pvcNode.Children = append(pvcNode.Children, pvNode)
+
+	driver := ""
+	if pv.Spec.CSI != nil {
+		driver = pv.Spec.CSI.Driver
+	}
+	if pv.Spec.StorageClassName != "" {
+		pvNode.Children = append(pvNode.Children, b.newStorageClassNode(pv.Spec.StorageClassName, driver))
+	} else if driver != "" {
+		pvNode.Children = append(pvNode.Children, newCSIDriverNode(driver))
+	}
+
+	for _, va := range b.resources.GetVolumeAttachments(pv.Name) {
+		vaNode := &Resource{
+			Kind:     "VolumeAttachment",
+			Name:     va.Name,
+			Object:   va,
+			Details:  []string{"node=" + va.Spec.NodeName},
+			Children: make([]*Resource, 0),
+		}
+		if va.Status.Attached {
+			vaNode.Details = append(vaNode.Details, "attached")
+		} else {
+			vaNode.Details = append(vaNode.Details, "not attached")
+		}
+		if va.Status.AttachError != nil {
+			vaNode.Details = append(vaNode.Details, fmt.Sprintf("error: %s", va.Status.AttachError.Message))
+		}
+		pvNode.Children = append(pvNode.Children, vaNode)
+	}
+
+	return pvcNode
+}
+
+// newStorageClassNode creates a StorageClass node with the CSI driver that provisions it.
+// The driver recorded on the volume wins over the StorageClass provisioner.
+func (b *Builder) newStorageClassNode(name, driver string) *Resource {
+	scNode := &Resource{
+		Kind:     "StorageClass",
+		Name:     name,
+		Children: make([]*Resource, 0),
+	}
+
+	sc := b.resources.GetStorageClass(name)
+	if sc != nil {
+		scNode.Object = sc
+		if sc.ReclaimPolicy != nil {
+			scNode.Details = append(scNode.Details, "reclaim="+string(*sc.ReclaimPolicy))
+		}
+		if sc.VolumeBindingMode != nil {
+			scNode.Details = append(scNode.Details, "binding="+string(*sc.VolumeBindingMode))
+		}
+		if driver == "" {
+			driver = sc.Provisioner
+		}
+	}
+
+	if driver != "" {
+		scNode.Children = append(scNode.Children, newCSIDriverNode(driver))
+	}
+
+	return scNode
+}
+
+// newCSIDriverNode creates a node for the driver backing a volume
+func newCSIDriverNode(driver string) *Resource {
+	return &Resource{
+		Kind:     "CSIDriver",
+		Name:     driver,
+		Children: make([]*Resource, 0),
+	}
+}
+
+// formatAccessModes joins access modes using their short names
+func formatAccessModes(modes []corev1.PersistentVolumeAccessMode) string {
+	names := make([]string, 0, len(modes))
+	for _, mode := range modes {
+		if name, ok := accessModeNames[mode]; ok {
+			names = append(names, name)
+		} else {
+			names = append(names, string(mode))
+		}
+	}
+	return strings.Join(names, ",")
+}
@@ -1,8 +1,38 @@
 package tree
 
+import (
+	"fmt"
+
+	"kubectl-tree/pkg/k8s"
+)
+
+// EmptyError is returned when a namespace has nothing for a tree to show
+type EmptyError struct {
+	Namespace string
+	What      string // What was looked for, e.g. "pods"
+}
+
+func (e *EmptyError) Error() string {
+	return fmt.Sprintf("no %s found in %s namespace", e.What, e.Namespace)
+}
+
 // Resource represents a Kubernetes resource in the tree
 type Resource struct {
-	Kind     string
-	Name     string
-	Children []*Resource
+	Kind      string       `json:"kind"`
+	Name      string       `json:"name"`
+	Namespace string       `json:"namespace,omitempty"` // Set only on foreign nodes from outside the tree's namespace
+	Object    interface{}  `json:"-"`                   // The API object or spec the node was built from, if any
+	Edge      k8s.EdgeType `json:"-"`                   // How the parent relates to the object, for nodes added by resolvers
+	Details   []string     `json:"details,omitempty"`   // Extra annotations shown after the name, e.g. "retained"
+	Usage     *Usage       `json:"usage,omitempty"`     // Live CPU and memory usage, when metrics are enabled
+	Children  []*Resource  `json:"children,omitempty"`
+}
+
+// DisplayDetails returns the details to show after the name, marking foreign
+// nodes with their namespace first
+func (r *Resource) DisplayDetails() []string {
+	if r.Namespace == "" {
+		return r.Details
+	}
+	return append([]string{"foreign ns=" + r.Namespace}, r.Details...)
 }
@@ -0,0 +1,149 @@
+package tree
+
+import (
+	"fmt"
+	"os"
+
+	corev1 "k8s.io/api/core/v1"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+// Usage thresholds, as a percentage of limit (or request without a limit)
+const (
+	usageWarnPercent     = 70
+	usageCriticalPercent = 90
+)
+
+// Usage holds live CPU and memory usage alongside the requests and limits it
+// counts against. CPU is in millicores and memory in bytes.
+type Usage struct {
+	CPU           int64 `json:"cpu"`
+	CPURequest    int64 `json:"cpuRequest"`
+	CPULimit      int64 `json:"cpuLimit"`
+	Memory        int64 `json:"memory"`
+	MemoryRequest int64 `json:"memoryRequest"`
+	MemoryLimit   int64 `json:"memoryLimit"`
+}
+
+// newContainerUsage combines a container's metrics with its requests and limits
+func newContainerUsage(container *corev1.Container, metrics *metricsv1beta1.ContainerMetrics) *Usage {
+	usage := &Usage{
+		CPURequest:    container.Resources.Requests.Cpu().MilliValue(),
+		CPULimit:      container.Resources.Limits.Cpu().MilliValue(),
+		MemoryRequest: container.Resources.Requests.Memory().Value(),
+		MemoryLimit:   container.Resources.Limits.Memory().Value(),
+	}
+	if metrics != nil {
+		usage.CPU = metrics.Usage.Cpu().MilliValue()
+		usage.Memory = metrics.Usage.Memory().Value()
+	}
+	return usage
+}
+
+// add sums another usage into this one
+func (u *Usage) add(other *Usage) {
+	u.CPU += other.CPU
+	u.CPURequest += other.CPURequest
+	u.CPULimit += other.CPULimit
+	u.Memory += other.Memory
+	u.MemoryRequest += other.MemoryRequest
+	u.MemoryLimit += other.MemoryLimit
+}
+
+// Percent returns the highest usage of CPU or memory as a percentage of its
+// limit, falling back to the request when no limit is set
+func (u *Usage) Percent() int64 {
+	return max(
+		percentOf(u.CPU, u.CPULimit, u.CPURequest),
+		percentOf(u.Memory, u.MemoryLimit, u.MemoryRequest),
+	)
+}
+
+// String formats usage with percentages of request and limit
+func (u *Usage) String() string {
+	return fmt.Sprintf("cpu=%dm%s mem=%dMi%s",
+		u.CPU, formatPercents(u.CPU, u.CPURequest, u.CPULimit),
+		u.Memory/(1024*1024), formatPercents(u.Memory, u.MemoryRequest, u.MemoryLimit),
+	)
+}
+
+// formatPercents renders used as a percentage of request and limit, skipping unset ones
+func formatPercents(used, request, limit int64) string {
+	switch {
+	case request > 0 && limit > 0:
+		return fmt.Sprintf(" (%d%% req, %d%% lim)", used*100/request, used*100/limit)
+	case request > 0:
+		return fmt.Sprintf(" (%d%% req)", used*100/request)
+	case limit > 0:
+		return fmt.Sprintf(" (%d%% lim)", used*100/limit)
+	default:
+		return ""
+	}
+}
+
+// percentOf returns used as a percentage of limit, or of request without a limit
+func percentOf(used, limit, request int64) int64 {
+	switch {
+	case limit > 0:
+		return used * 100 / limit
+	case request > 0:
+		return used * 100 / request
+	default:
+		return 0
+	}
+}
+
+// loadMetrics fetches pod metrics for the namespace. The metrics API is an
+// optional add-on, so failures are reported and the tree is built without usage.
+func (b *Builder) loadMetrics(namespace string) {
+	if !b.options.Metrics {
+		return
+	}
+
+	metrics, err := b.client.GetPodMetrics(namespace)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: resource usage unavailable: %v\n", err)
+		return
+	}
+
+	b.podMetrics = make(map[string]*metricsv1beta1.PodMetrics)
+	for i := range metrics.Items {
+		b.podMetrics[metrics.Items[i].Name] = &metrics.Items[i]
+	}
+}
+
+// addContainerUsage sets usage on a container node from its pod's metrics
+func (b *Builder) addContainerUsage(containerNode *Resource, container *corev1.Container, pod *corev1.Pod) {
+	podMetrics, ok := b.podMetrics[pod.Name]
+	if !ok {
+		return
+	}
+
+	var containerMetrics *metricsv1beta1.ContainerMetrics
+	for i := range podMetrics.Containers {
+		if podMetrics.Containers[i].Name == container.Name {
+			containerMetrics = &podMetrics.Containers[i]
+			break
+		}
+	}
+	containerNode.Usage = newContainerUsage(container, containerMetrics)
+}
+
+// rollupUsage gives every node without usage of its own the total of its
+// children, so pods, ReplicaSets, workloads and the namespace show sums
+func rollupUsage(node *Resource) *Usage {
+	var total *Usage
+	for _, child := range node.Children {
+		if usage := rollupUsage(child); usage != nil {
+			if total == nil {
+				total = &Usage{}
+			}
+			total.add(usage)
+		}
+	}
+
+	if node.Usage == nil {
+		node.Usage = total
+	}
+	return node.Usage
+}
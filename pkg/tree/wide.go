@@ -0,0 +1,112 @@
+package tree
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// digestLength is how much of an image digest to show, like a short git hash
+const digestLength = len("sha256:") + 12
+
+// podDetails returns the QoS class, IP and node of a pod for the wide view
+func podDetails(pod *corev1.Pod) []string {
+	var details []string
+	if pod.Status.QOSClass != "" {
+		details = append(details, "qos="+string(pod.Status.QOSClass))
+	}
+	if pod.Status.PodIP != "" {
+		details = append(details, "ip="+pod.Status.PodIP)
+	}
+	if pod.Spec.NodeName != "" {
+		details = append(details, "node="+pod.Spec.NodeName)
+	}
+	return details
+}
+
+// containerDetails returns the image, resources, ports and probes of a container
+// for the wide view. The image digest comes from the matching container status.
+func containerDetails(container *corev1.Container, statuses []corev1.ContainerStatus) []string {
+	image := container.Image
+	for _, status := range statuses {
+		if status.Name == container.Name {
+			if digest := imageDigest(status.ImageID); digest != "" {
+				image += "@" + digest
+			}
+			break
+		}
+	}
+	details := []string{"image=" + image}
+
+	details = append(details,
+		"cpu="+formatRequestLimit(container.Resources, corev1.ResourceCPU),
+		"mem="+formatRequestLimit(container.Resources, corev1.ResourceMemory),
+	)
+
+	if len(container.Ports) > 0 {
+		ports := make([]string, 0, len(container.Ports))
+		for _, port := range container.Ports {
+			ports = append(ports, fmt.Sprintf("%d/%s", port.ContainerPort, port.Protocol))
+		}
+		details = append(details, "ports="+strings.Join(ports, ","))
+	}
+
+	var probes []string
+	if container.StartupProbe != nil {
+		probes = append(probes, "startup:"+probeType(container.StartupProbe))
+	}
+	if container.LivenessProbe != nil {
+		probes = append(probes, "liveness:"+probeType(container.LivenessProbe))
+	}
+	if container.ReadinessProbe != nil {
+		probes = append(probes, "readiness:"+probeType(container.ReadinessProbe))
+	}
+	if len(probes) > 0 {
+		details = append(details, "probes="+strings.Join(probes, ","))
+	}
+
+	return details
+}
+
+// imageDigest extracts a shortened digest from a container status image ID
+// such as docker-pullable://nginx@sha256:abc...
+func imageDigest(imageID string) string {
+	i := strings.Index(imageID, "sha256:")
+	if i < 0 {
+		return ""
+	}
+	digest := imageID[i:]
+	if len(digest) > digestLength {
+		digest = digest[:digestLength]
+	}
+	return digest
+}
+
+// formatRequestLimit formats a resource as request/limit, with - for unset values
+func formatRequestLimit(resources corev1.ResourceRequirements, name corev1.ResourceName) string {
+	request, limit := "-", "-"
+	if quantity, ok := resources.Requests[name]; ok {
+		request = quantity.String()
+	}
+	if quantity, ok := resources.Limits[name]; ok {
+		limit = quantity.String()
+	}
+	return request + "/" + limit
+}
+
+// probeType returns the handler a probe uses
+func probeType(probe *corev1.Probe) string {
+	switch {
+	case probe.HTTPGet != nil:
+		return "http"
+	case probe.TCPSocket != nil:
+		return "tcp"
+	case probe.GRPC != nil:
+		return "grpc"
+	case probe.Exec != nil:
+		return "exec"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,140 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"kubectl-tree/pkg/tree"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+// toYAML renders the object behind a node. Managed fields are dropped as noise
+// and Secret values are never shown.
+func toYAML(node *tree.Resource) string {
+	if node.Object == nil {
+		return fmt.Sprintf("No object behind %s/%s", node.Kind, node.Name)
+	}
+
+	obj := node.Object
+	header := ""
+	if runtimeObj, ok := obj.(runtime.Object); ok {
+		copied := runtimeObj.DeepCopyObject()
+		if meta, ok := copied.(metav1.Object); ok {
+			meta.SetManagedFields(nil)
+		}
+		if secret, ok := copied.(*corev1.Secret); ok {
+			header = fmt.Sprintf("# data keys: %s (values hidden)\n", strings.Join(secretKeys(secret), ", "))
+			secret.Data = nil
+			secret.StringData = nil
+			// The last applied configuration repeats the whole Secret, values included
+			annotations := secret.GetAnnotations()
+			delete(annotations, corev1.LastAppliedConfigAnnotation)
+			if len(annotations) == 0 {
+				secret.SetAnnotations(nil)
+			}
+		}
+		obj = copied
+	}
+
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		return fmt.Sprintf("Error rendering YAML: %v", err)
+	}
+	return header + string(data)
+}
+
+// describe renders a short kubectl describe style summary of a node
+func describe(node *tree.Resource) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Kind:       %s\n", node.Kind)
+	fmt.Fprintf(&b, "Name:       %s\n", node.Name)
+
+	if meta, ok := node.Object.(metav1.Object); ok {
+		if meta.GetNamespace() != "" {
+			fmt.Fprintf(&b, "Namespace:  %s\n", meta.GetNamespace())
+		}
+		created := meta.GetCreationTimestamp()
+		if !created.IsZero() {
+			fmt.Fprintf(&b, "Created:    %s (%s ago)\n", created.Format(time.RFC3339), time.Since(created.Time).Round(time.Second))
+		}
+		writeMap(&b, "Labels", meta.GetLabels())
+		annotations := make(map[string]string)
+		for key, value := range meta.GetAnnotations() {
+			// The last applied configuration repeats the whole object
+			if key != corev1.LastAppliedConfigAnnotation {
+				annotations[key] = value
+			}
+		}
+		writeMap(&b, "Annotations", annotations)
+		if owners := meta.GetOwnerReferences(); len(owners) > 0 {
+			b.WriteString("Owners:\n")
+			for _, owner := range owners {
+				fmt.Fprintf(&b, "  %s/%s\n", owner.Kind, owner.Name)
+			}
+		}
+	}
+
+	if secret, ok := node.Object.(*corev1.Secret); ok {
+		fmt.Fprintf(&b, "Type:       %s\n", secret.Type)
+		fmt.Fprintf(&b, "Data keys:  %s\n", strings.Join(secretKeys(secret), ", "))
+	}
+
+	if len(node.Details) > 0 {
+		fmt.Fprintf(&b, "Details:    %s\n", strings.Join(node.Details, ", "))
+	}
+
+	if len(node.Children) > 0 {
+		counts := make(map[string]int)
+		for _, child := range node.Children {
+			counts[child.Kind]++
+		}
+		b.WriteString("Children:\n")
+		for _, kind := range sortedKeys(counts) {
+			fmt.Fprintf(&b, "  %s: %d\n", kind, counts[kind])
+		}
+	}
+
+	return b.String()
+}
+
+// writeMap writes a titled, sorted key=value list
+func writeMap(b *strings.Builder, title string, values map[string]string) {
+	if len(values) == 0 {
+		fmt.Fprintf(b, "%-11s <none>\n", title+":")
+		return
+	}
+	fmt.Fprintf(b, "%s:\n", title)
+	for _, key := range sortedKeys(values) {
+		fmt.Fprintf(b, "  %s=%s\n", key, values[key])
+	}
+}
+
+// secretKeys returns the sorted data keys of a Secret
+func secretKeys(secret *corev1.Secret) []string {
+	keys := make([]string, 0, len(secret.Data)+len(secret.StringData))
+	for key := range secret.Data {
+		keys = append(keys, key)
+	}
+	for key := range secret.StringData {
+		if _, ok := secret.Data[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
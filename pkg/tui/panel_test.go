@@ -0,0 +1,76 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	"kubectl-tree/pkg/tree"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestToYAMLHidesSecretValues(t *testing.T) {
+	lastApplied := `{"apiVersion":"v1","kind":"Secret","metadata":{"name":"db"},"stringData":{"password":"hunter2"}}`
+	tests := []struct {
+		name   string
+		secret *corev1.Secret
+	}{
+		{
+			name: "data",
+			secret: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "db"},
+				Data:       map[string][]byte{"password": []byte("hunter2")},
+			},
+		},
+		{
+			name: "stringData",
+			secret: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "db"},
+				StringData: map[string]string{"password": "hunter2"},
+			},
+		},
+		{
+			name: "last applied configuration",
+			secret: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "db",
+					Annotations: map[string]string{corev1.LastAppliedConfigAnnotation: lastApplied, "team": "payments"},
+				},
+				Data: map[string][]byte{"password": []byte("hunter2")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &tree.Resource{Kind: "Secret", Name: tt.secret.Name, Object: tt.secret}
+			for view, out := range map[string]string{"yaml": toYAML(node), "describe": describe(node)} {
+				if strings.Contains(out, "hunter2") || strings.Contains(out, "aHVudGVyMg") {
+					t.Errorf("%s view shows the secret value:\n%s", view, out)
+				}
+				if !strings.Contains(out, "password") {
+					t.Errorf("%s view does not list the data key:\n%s", view, out)
+				}
+			}
+		})
+	}
+}
+
+func TestToYAMLKeepsSecretObject(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "db",
+			Annotations: map[string]string{corev1.LastAppliedConfigAnnotation: "{}", "team": "payments"},
+		},
+		Data: map[string][]byte{"password": []byte("hunter2")},
+	}
+	toYAML(&tree.Resource{Kind: "Secret", Name: "db", Object: secret})
+
+	if string(secret.Data["password"]) != "hunter2" {
+		t.Errorf("toYAML changed the Secret's data")
+	}
+	if _, ok := secret.Annotations[corev1.LastAppliedConfigAnnotation]; !ok {
+		t.Errorf("toYAML changed the Secret's annotations")
+	}
+}
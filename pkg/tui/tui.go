@@ -0,0 +1,504 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"kubectl-tree/pkg/k8s"
+	"kubectl-tree/pkg/tree"
+
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// logTailLines is how many lines of logs the logs panel fetches
+const logTailLines = 200
+
+// helpText is shown in the status line when nothing else is
+const helpText = "↑↓ move  ←→ collapse/expand  enter toggle  / search  n/N next/prev  y yaml  d describe  l logs  c copy name  pgup/pgdn scroll panel  q quit"
+
+// panelMode selects what the side panel shows for the selected node
+type panelMode int
+
+const (
+	panelYAML panelMode = iota
+	panelDescribe
+	panelLogs
+)
+
+// Colors match the ones tree.Printer uses for each kind
+var kindColors = map[string]lipgloss.Color{
+	"Deployment":            "4",
+	"StatefulSet":           "4",
+	"DaemonSet":             "4",
+	"Pod":                   "2",
+	"Service":               "3",
+	"EndpointSlice":         "3",
+	"Endpoint":              "3",
+	"ConfigMap":             "5",
+	"Secret":                "5",
+	"PersistentVolumeClaim": "6",
+	"PersistentVolume":      "6",
+	"StorageClass":          "6",
+	"Event":                 "1",
+}
+
+var (
+	selectedStyle = lipgloss.NewStyle().Reverse(true)
+	detailStyle   = lipgloss.NewStyle().Faint(true)
+	panelStyle    = lipgloss.NewStyle().BorderStyle(lipgloss.NormalBorder()).BorderLeft(true)
+	titleStyle    = lipgloss.NewStyle().Bold(true)
+)
+
+// row is a visible line of the tree
+type row struct {
+	node   *tree.Resource
+	prefix string
+}
+
+// logsMsg carries logs fetched in the background
+type logsMsg struct {
+	node *tree.Resource
+	text string
+	err  error
+}
+
+// model is the bubbletea model for the tree browser
+type model struct {
+	client    *k8s.Client
+	namespace string
+
+	root     *tree.Resource
+	order    []*tree.Resource // All nodes in display order, for searching
+	parents  map[*tree.Resource]*tree.Resource
+	expanded map[*tree.Resource]bool
+	rows     []row
+
+	cursor int
+	offset int
+	width  int
+	height int
+
+	searching bool
+	query     string
+
+	panel       panelMode
+	panelOffset int
+	logsFor     *tree.Resource
+	logs        string
+	status      string
+}
+
+// Run starts the interactive terminal UI over a built resource tree
+func Run(root *tree.Resource, client *k8s.Client, namespace string) error {
+	if root == nil {
+		return nil
+	}
+
+	m := &model{
+		client:    client,
+		namespace: namespace,
+		root:      root,
+		parents:   make(map[*tree.Resource]*tree.Resource),
+		expanded:  make(map[*tree.Resource]bool),
+	}
+	m.index(root, nil, 0)
+	m.refreshRows()
+
+	_, err := tea.NewProgram(m, tea.WithAltScreen()).Run()
+	return err
+}
+
+// index records parents and display order, expanding the top two levels
+func (m *model) index(node, parent *tree.Resource, depth int) {
+	m.order = append(m.order, node)
+	m.parents[node] = parent
+	if depth < 2 {
+		m.expanded[node] = true
+	}
+	for _, child := range node.Children {
+		m.index(child, node, depth+1)
+	}
+}
+
+// refreshRows rebuilds the visible rows from the expanded state
+func (m *model) refreshRows() {
+	m.rows = m.rows[:0]
+	m.addRows(m.root, "", true)
+	if m.cursor >= len(m.rows) {
+		m.cursor = len(m.rows) - 1
+	}
+}
+
+func (m *model) addRows(node *tree.Resource, prefix string, isLast bool) {
+	connector := "├── "
+	childPrefix := prefix + "│   "
+	if isLast {
+		connector = "└── "
+		childPrefix = prefix + "    "
+	}
+	m.rows = append(m.rows, row{node: node, prefix: prefix + connector})
+
+	if !m.expanded[node] {
+		return
+	}
+	for i, child := range node.Children {
+		m.addRows(child, childPrefix, i == len(node.Children)-1)
+	}
+}
+
+// selected returns the node under the cursor
+func (m *model) selected() *tree.Resource {
+	if m.cursor < 0 || m.cursor >= len(m.rows) {
+		return nil
+	}
+	return m.rows[m.cursor].node
+}
+
+// moveTo puts the cursor on a node, expanding its ancestors so it is visible
+func (m *model) moveTo(node *tree.Resource) {
+	for parent := m.parents[node]; parent != nil; parent = m.parents[parent] {
+		m.expanded[parent] = true
+	}
+	m.refreshRows()
+	for i, r := range m.rows {
+		if r.node == node {
+			m.setCursor(i)
+			return
+		}
+	}
+}
+
+// setCursor moves the cursor and keeps it on screen
+func (m *model) setCursor(cursor int) {
+	if cursor < 0 {
+		cursor = 0
+	}
+	if cursor >= len(m.rows) {
+		cursor = len(m.rows) - 1
+	}
+	if cursor != m.cursor {
+		m.panelOffset = 0
+	}
+	m.cursor = cursor
+
+	height := m.bodyHeight()
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if height > 0 && m.cursor >= m.offset+height {
+		m.offset = m.cursor - height + 1
+	}
+}
+
+func (m *model) bodyHeight() int {
+	return m.height - 1
+}
+
+// search moves to the next node matching the query, starting at the current node
+// when inclusive is set, and wrapping around the tree in the given direction
+func (m *model) search(forward, inclusive bool) {
+	if m.query == "" || len(m.order) == 0 {
+		return
+	}
+
+	current := 0
+	for i, node := range m.order {
+		if node == m.selected() {
+			current = i
+			break
+		}
+	}
+
+	query := strings.ToLower(m.query)
+	step := 1
+	if !forward {
+		step = -1
+	}
+	start := current + step
+	if inclusive {
+		start = current
+	}
+	for n := 0; n < len(m.order); n++ {
+		i := ((start+n*step)%len(m.order) + len(m.order)) % len(m.order)
+		node := m.order[i]
+		if strings.Contains(strings.ToLower(node.Kind+"/"+node.Name), query) {
+			m.moveTo(node)
+			m.status = ""
+			return
+		}
+	}
+	m.status = fmt.Sprintf("no match for %q", m.query)
+}
+
+func (m *model) Init() tea.Cmd {
+	return nil
+}
+
+func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.setCursor(m.cursor)
+	case logsMsg:
+		m.logsFor = msg.node
+		if msg.err != nil {
+			m.logs = msg.err.Error()
+		} else {
+			m.logs = msg.text
+		}
+		m.panel = panelLogs
+		m.panelOffset = 0
+	case tea.KeyMsg:
+		if m.searching {
+			return m, m.updateSearch(msg)
+		}
+		return m, m.updateBrowse(msg)
+	}
+	return m, nil
+}
+
+// updateSearch handles keys while typing a search query
+func (m *model) updateSearch(msg tea.KeyMsg) tea.Cmd {
+	switch msg.Type {
+	case tea.KeyEnter:
+		m.searching = false
+	case tea.KeyEsc, tea.KeyCtrlC:
+		m.searching = false
+		m.query = ""
+	case tea.KeyBackspace:
+		if len(m.query) > 0 {
+			runes := []rune(m.query)
+			m.query = string(runes[:len(runes)-1])
+			m.search(true, true)
+		}
+	case tea.KeySpace:
+		m.query += " "
+		m.search(true, true)
+	case tea.KeyRunes:
+		m.query += string(msg.Runes)
+		m.search(true, true)
+	}
+	return nil
+}
+
+// updateBrowse handles keys while navigating the tree
+func (m *model) updateBrowse(msg tea.KeyMsg) tea.Cmd {
+	m.status = ""
+	node := m.selected()
+
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return tea.Quit
+	case "up", "k":
+		m.setCursor(m.cursor - 1)
+	case "down", "j":
+		m.setCursor(m.cursor + 1)
+	case "home", "g":
+		m.setCursor(0)
+	case "end", "G":
+		m.setCursor(len(m.rows) - 1)
+	case "right":
+		if node != nil && len(node.Children) > 0 {
+			m.expanded[node] = true
+			m.refreshRows()
+		}
+	case "left":
+		if node == nil {
+			break
+		}
+		if m.expanded[node] && len(node.Children) > 0 {
+			m.expanded[node] = false
+			m.refreshRows()
+		} else if parent := m.parents[node]; parent != nil {
+			m.moveTo(parent)
+		}
+	case "enter", " ":
+		if node != nil && len(node.Children) > 0 {
+			m.expanded[node] = !m.expanded[node]
+			m.refreshRows()
+		}
+	case "/":
+		m.searching = true
+		m.query = ""
+	case "n":
+		m.search(true, false)
+	case "N":
+		m.search(false, false)
+	case "y":
+		m.panel = panelYAML
+		m.panelOffset = 0
+	case "d":
+		m.panel = panelDescribe
+		m.panelOffset = 0
+	case "l":
+		return m.fetchLogs(node)
+	case "c":
+		if node != nil {
+			if err := clipboard.WriteAll(node.Name); err != nil {
+				m.status = fmt.Sprintf("copy failed: %v", err)
+			} else {
+				m.status = fmt.Sprintf("copied %s", node.Name)
+			}
+		}
+	case "pgdown", "ctrl+d":
+		m.panelOffset += m.bodyHeight() / 2
+	case "pgup", "ctrl+u":
+		m.panelOffset -= m.bodyHeight() / 2
+		if m.panelOffset < 0 {
+			m.panelOffset = 0
+		}
+	}
+	return nil
+}
+
+// fetchLogs loads logs for a pod or container node in the background
+func (m *model) fetchLogs(node *tree.Resource) tea.Cmd {
+	if node == nil {
+		return nil
+	}
+
+	var pod, container string
+	switch node.Kind {
+	case "Pod":
+		// Use the first container, as kubectl logs does without a default set
+		pod = node.Name
+		for _, child := range node.Children {
+			if child.Kind == "Container" {
+				container = child.Name
+				break
+			}
+		}
+	case "Container", "InitContainer", "SidecarContainer", "EphemeralContainer":
+		if parent := m.parents[node]; parent != nil && parent.Kind == "Pod" {
+			pod = parent.Name
+			container = node.Name
+		}
+	}
+	if pod == "" {
+		m.status = "logs are only available for pods and containers"
+		return nil
+	}
+
+	m.status = fmt.Sprintf("fetching logs for %s/%s...", pod, container)
+	client, namespace := m.client, m.namespace
+	return func() tea.Msg {
+		text, err := client.GetPodLogs(namespace, pod, container, logTailLines)
+		return logsMsg{node: node, text: text, err: err}
+	}
+}
+
+func (m *model) View() string {
+	if m.width == 0 || m.height == 0 {
+		return ""
+	}
+
+	treeWidth := m.width / 2
+	panelWidth := m.width - treeWidth - 1
+	height := m.bodyHeight()
+
+	left := lipgloss.NewStyle().Width(treeWidth).Height(height).Render(m.renderTree(treeWidth, height))
+	right := panelStyle.Width(panelWidth).Height(height).Render(m.renderPanel(panelWidth, height))
+
+	return lipgloss.JoinHorizontal(lipgloss.Top, left, right) + "\n" + m.renderStatus()
+}
+
+// renderTree draws the visible rows, truncated to width
+func (m *model) renderTree(width, height int) string {
+	var lines []string
+	for i := m.offset; i < len(m.rows) && i < m.offset+height; i++ {
+		r := m.rows[i]
+		node := r.node
+
+		marker := "  "
+		if len(node.Children) > 0 {
+			marker = "▸ "
+			if m.expanded[node] {
+				marker = "▾ "
+			}
+		}
+
+		prefix := truncate(r.prefix+marker, width)
+		label := truncate(node.Kind+"/"+node.Name, width-runeCount(prefix))
+		details := ""
+		if nodeDetails := node.DisplayDetails(); len(nodeDetails) > 0 {
+			details = " (" + strings.Join(nodeDetails, ", ") + ")"
+		}
+		if node.Usage != nil {
+			details += " [" + node.Usage.String() + "]"
+		}
+		details = truncate(details, width-runeCount(prefix)-runeCount(label))
+
+		if i == m.cursor {
+			lines = append(lines, prefix+selectedStyle.Render(label)+details)
+			continue
+		}
+		if color, ok := kindColors[node.Kind]; ok {
+			label = lipgloss.NewStyle().Foreground(color).Render(label)
+		}
+		lines = append(lines, prefix+label+detailStyle.Render(details))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renderPanel draws the side panel for the selected node
+func (m *model) renderPanel(width, height int) string {
+	node := m.selected()
+	if node == nil {
+		return ""
+	}
+
+	var title, text string
+	switch {
+	case m.panel == panelLogs && m.logsFor == node:
+		title, text = "Logs", m.logs
+	case m.panel == panelDescribe:
+		title, text = "Describe", describe(node)
+	default:
+		title, text = "YAML", toYAML(node)
+	}
+
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	if m.panelOffset > len(lines)-1 {
+		m.panelOffset = len(lines) - 1
+	}
+	lines = lines[m.panelOffset:]
+	if len(lines) > height-1 {
+		lines = lines[:height-1]
+	}
+
+	out := []string{titleStyle.Render(truncate(fmt.Sprintf("%s: %s/%s", title, node.Kind, node.Name), width))}
+	for _, line := range lines {
+		out = append(out, truncate(strings.ReplaceAll(line, "\t", "    "), width))
+	}
+	return strings.Join(out, "\n")
+}
+
+// renderStatus draws the search prompt, a status message or the key help
+func (m *model) renderStatus() string {
+	switch {
+	case m.searching:
+		return truncate("/"+m.query, m.width)
+	case m.status != "":
+		return truncate(m.status, m.width)
+	default:
+		return detailStyle.Render(truncate(helpText, m.width))
+	}
+}
+
+// truncate cuts a string to at most width runes
+func truncate(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+	return string(runes[:width])
+}
+
+func runeCount(s string) int {
+	return len([]rune(s))
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/clientcmd/api"
@@ -29,6 +30,27 @@ func GetCurrentNamespace(kubeconfig string) (string, error) {
 	return getCurrentNamespaceFromConfig(config)
 }
 
+// GetContexts returns the names of all contexts in the kubeconfig, sorted
+func GetContexts(kubeconfig string) ([]string, error) {
+	if kubeconfig == "" {
+		if home := os.Getenv("HOME"); home != "" {
+			kubeconfig = filepath.Join(home, ".kube", "config")
+		}
+	}
+
+	config, err := clientcmd.LoadFromFile(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig: %v", err)
+	}
+
+	contexts := make([]string, 0, len(config.Contexts))
+	for name := range config.Contexts {
+		contexts = append(contexts, name)
+	}
+	sort.Strings(contexts)
+	return contexts, nil
+}
+
 // getCurrentNamespaceFromConfig extracts the current namespace from the config
 func getCurrentNamespaceFromConfig(config *api.Config) (string, error) {
 	if config == nil {